@@ -127,5 +127,9 @@ INSERT INTO ingredient_recipe (id, ingredient_id, recipe_id, quantity, unit_of_m
   (5, 4, 2, "0.15", "Liter");
 
 COMMIT;
+
+DROP PROCEDURE IF EXISTS reserve_equipment;
+CREATE PROCEDURE reserve_equipment(IN equipment_id INT, IN quantity REAL, OUT equipment_name TEXT, OUT reserved REAL)
+  SELECT name, quantity INTO equipment_name, reserved FROM equipment WHERE id = equipment_id;
 __EOF__
 
@@ -144,4 +144,10 @@ INSERT INTO "ingredient_recipe" ("id", "ingredient_id", "recipe_id", "quantity",
   VALUES (4, 3, 2, 20, 'Gram');
 INSERT INTO "ingredient_recipe" ("id", "ingredient_id", "recipe_id", "quantity", "unit_of_measure")
   VALUES (5, 4, 2, 0.15, 'Liter');
+
+CREATE OR REPLACE PROCEDURE reserve_equipment(equipment_id IN NUMBER, quantity IN NUMBER, equipment_name OUT NVARCHAR2, reserved OUT NUMBER) AS
+BEGIN
+  SELECT "name", quantity INTO equipment_name, reserved FROM "equipment" WHERE "id" = equipment_id;
+END;
+/
 __EOF__
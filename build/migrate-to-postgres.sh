@@ -138,6 +138,10 @@ INSERT INTO ingredient_recipe (id, ingredient_id, recipe_id, quantity, unit_of_m
   (4, 3, 2, '20', 'Gram'),
   (5, 4, 2, '0.15', 'Liter');
 
+CREATE OR REPLACE FUNCTION reserve_equipment(equipment_id INT, quantity REAL, OUT equipment_name TEXT, OUT reserved REAL) AS \$\$
+  SELECT name, quantity FROM equipment WHERE id = equipment_id
+\$\$ LANGUAGE sql;
+
 COMMIT;
 __EOF__
 
@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/audit"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// Auditor returns the auditor configured in the `audit` section of the
+// config file, or nil if auditing is disabled
+func (b *Backend) Auditor(ctx context.Context) *audit.Auditor {
+	cfg := config.Current(ctx).Audit
+	if !cfg.Enabled {
+		return nil
+	}
+	b.auditorOnce.Do(func() {
+		// Audit records are never part of the transaction of the request.
+		// The route tells the backend that the insert returns no id.
+		exec := func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+			ctx = context.WithValue(ctx, util.ContextKey("tx"), "")
+			ctx = context.WithValue(ctx, util.ContextKey("currentRoute"), "Audit")
+			return b.ExecContext(ctx, query, args...)
+		}
+		auditor, err := audit.NewFromConfig(cfg, exec, b.FormatPlaceholder)
+		if err != nil {
+			log.When(true).Errorf("[backend] unable to initialize audit log: %s\n", err)
+			return
+		}
+		b.auditor = auditor
+	})
+	return b.auditor
+}
+
+// pendingAudit holds the audit records of write operations performed
+// within a transaction, which are only logged once the transaction was
+// committed
+type pendingAudit struct {
+	sync.Mutex
+	records map[string][]*audit.Record
+}
+
+func (p *pendingAudit) add(tx string, record *audit.Record) {
+	p.Lock()
+	defer p.Unlock()
+	if p.records == nil {
+		p.records = make(map[string][]*audit.Record)
+	}
+	p.records[tx] = append(p.records[tx], record)
+}
+
+// remove returns the pending records of the transaction and forgets them
+func (p *pendingAudit) remove(tx string) []*audit.Record {
+	p.Lock()
+	defer p.Unlock()
+	records := p.records[tx]
+	delete(p.records, tx)
+	return records
+}
+
+// audit records a successful write operation on the requested type
+// descriptor. The before and after maps contain the values of the
+// affected columns. Write operations within a transaction are recorded
+// when the transaction is committed.
+func (b *Backend) audit(req *http.Request, operation, id string, before, after map[string]interface{}) {
+	auditor := b.Auditor(req.Context())
+	if auditor == nil {
+		return
+	}
+	record := newAuditRecord(req, operation)
+	record.ID = id
+	table, _ := req.Context().Value(util.ContextKey("table")).(string)
+	if td := util.GetTypeDescriptorUsingDBTableName(config.Current(req.Context()).Descriptor.TypeDescriptors, table); td != nil {
+		record.TypeDescriptor = td.Key
+		record.Changes = changes(td, before, after)
+	}
+	if record.Tx != "" {
+		b.pendingAudit.add(record.Tx, record)
+		return
+	}
+	auditor.Log(record)
+}
+
+// auditCommit records the write operations performed within a transaction
+// followed by the successful commit of the transaction
+func (b *Backend) auditCommit(req *http.Request, tx string) {
+	auditor := b.Auditor(req.Context())
+	if auditor == nil {
+		return
+	}
+	for _, record := range b.pendingAudit.remove(tx) {
+		auditor.Log(record)
+	}
+	record := newAuditRecord(req, "CommitTx")
+	record.Tx = tx
+	auditor.Log(record)
+}
+
+// discardAudit forgets the write operations performed within a transaction
+// that was rolled back or expired before it was committed
+func (b *Backend) discardAudit(ctx context.Context, tx string) {
+	if records := b.pendingAudit.remove(tx); len(records) > 0 {
+		log.When(config.Logging()).WithContext(ctx).Infof(
+			"[backend] discarded %d audit records of transaction %s: "+
+				"transaction was not committed\n",
+			len(records), tx,
+		)
+	}
+}
+
+func newAuditRecord(req *http.Request, operation string) *audit.Record {
+	record := &audit.Record{
+		Timestamp: time.Now().UTC(),
+		Operation: operation,
+	}
+	if principal, ok := req.Context().Value(util.ContextKey("principal")).(*util.Principal); ok {
+		record.Principal = principal.Username
+	}
+	record.Tx, _ = req.Context().Value(util.ContextKey("tx")).(string)
+	return record
+}
+
+// changes maps the column values before and after a write operation to
+// the fields of the type descriptor, omitting fields that were not
+// changed
+func changes(td *descriptor.TypeDescriptor, before, after map[string]interface{}) map[string]*audit.Change {
+	result := make(map[string]*audit.Change)
+	add := func(key, column string) {
+		if column == "" {
+			return
+		}
+		beforeValue, inBefore := before[column]
+		afterValue, inAfter := after[column]
+		// Columns that were not written to are unchanged, unless the
+		// whole resource was deleted
+		if (after != nil && !inAfter) || (!inBefore && !inAfter) {
+			return
+		}
+		if inBefore && inAfter && fmt.Sprint(beforeValue) == fmt.Sprint(afterValue) {
+			return
+		}
+		result[key] = &audit.Change{Before: beforeValue, After: afterValue}
+	}
+	for _, field := range td.Fields {
+		if field.Type.Name == "money" {
+			add(field.Type.Amount.Key, field.Type.Amount.FromColumn)
+			add(field.Type.Currency.Key, field.Type.Currency.FromColumn)
+			continue
+		}
+		add(field.Key, field.FromColumn)
+	}
+	return result
+}
+
+// requestDataByColumn maps the request data, which is keyed by the fields
+// of the type descriptor, to the columns of the database table
+func requestDataByColumn(ctx context.Context, table string, requestData map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, table)
+	if td == nil {
+		return result
+	}
+	for _, field := range td.Fields {
+		if field.Type.Name == "money" {
+			if value, ok := requestData[field.Type.Amount.Key]; ok {
+				result[field.Type.Amount.FromColumn] = value
+			}
+			if value, ok := requestData[field.Type.Currency.Key]; ok {
+				result[field.Type.Currency.FromColumn] = value
+			}
+			continue
+		}
+		if value, ok := requestData[field.Key]; ok && field.FromColumn != "" {
+			result[field.FromColumn] = value
+		}
+	}
+	return result
+}
+
+// currentColumnValues fetches the column values of the resource with the
+// given id as they are before a write operation. It returns nil if
+// auditing is disabled, since the values are only needed for the audit log.
+func (b *Backend) currentColumnValues(req *http.Request, id string) map[string]interface{} {
+	if b.Auditor(req.Context()) == nil {
+		return nil
+	}
+	values, err := b.columnValues(req, id)
+	if err != nil || values == nil {
+		log.When(config.Logging()).WithContext(req.Context()).Infof(
+			"[handler] unable to fetch resource '%s' for the audit log: %v\n", id, err,
+		)
+		return nil
+	}
+	return values
+}
+
+// columnValues fetches the column values of the resource with the given
+// id, restricted by the row filter of the requested type descriptor. It
+// returns nil if there is no such resource.
+func (b *Backend) columnValues(req *http.Request, id string) (map[string]interface{}, error) {
+	table := req.Context().Value(util.ContextKey("table")).(string)
+	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, 2)
+	if err != nil {
+		return nil, err
+	}
+	queryTemplate := &query.QueryTemplate{
+		Vars: []string{b.GetQueryTemplate("GetSingle")},
+		TemplateData: struct {
+			TableName      string
+			Relations      []*descriptor.Field
+			UniqueIdColumn string
+			RowFilter      string
+		}{
+			TableName:      table,
+			UniqueIdColumn: uniqueIDColumn,
+			RowFilter:      rowFilter,
+		},
+		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+		TableSource:    b.TableSource,
+	}
+	ctx := context.WithValue(req.Context(), util.ContextKey("currentRoute"), "GetSingle")
+	ctx = context.WithValue(ctx, util.ContextKey("relationships"), []*descriptor.Field(nil))
+	queryString, _, err := queryTemplate.Interpolate(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	results, err := b.QueryContext(ctx, queryString, append([]interface{}{id}, rowFilterArgs...)...)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	values, _ := results[0].(map[string]interface{})[table].(map[string]interface{})
+	return values, nil
+}
@@ -6,18 +6,32 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	uuid "github.com/satori/go.uuid"
+	"github.com/signavio/workflow-connector/internal/pkg/audit"
+	"github.com/signavio/workflow-connector/internal/pkg/circuitbreaker"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrUnexpectedJSON         = errors.New("Received JSON data that we are unable to parse")
 	ErrMismatchedAffectedRows = errors.New("The amount of rows affected should be sane")
 	ErrNoLastInsertID         = errors.New("Database does not support getting the last inserted ID")
+	ErrProceduresUnsupported  = errors.New("Database does not support calling stored procedures")
 )
 
 type Backend struct {
@@ -27,17 +41,52 @@ type Backend struct {
 	QueryFormatFuncs              map[string]func() string
 	BackendFormattingFuncs        map[string]func(string) (string, error)
 	CastBackendTypeToGolangType   func(string) interface{}
+	FormatPlaceholder             func(int) string
+	PingFunc                      func(context.Context) error
 	QueryContextFunc              func(context.Context, string, ...interface{}) ([]interface{}, error)
 	ExecContextFunc               func(context.Context, string, ...interface{}) (sql.Result, error)
+	ExecProcedureFunc             func(context.Context, *descriptor.Action, ...interface{}) ([]map[string]interface{}, error)
 	OpenFunc                      func(...interface{}) error
 	CreateTxFunc                  func(time.Duration) (uuid.UUID, error)
 	CommitTxFunc                  func(string) error
+	CloseFunc                     func() error
+	ReloadFunc                    func() error
+	IsTransientErrorFunc          func(error) bool
+	IsConflictErrorFunc           func(error) bool
+	ExplainFunc                   func(context.Context, string, ...interface{}) (string, error)
+	TableSourceFunc               func(context.Context, string) (string, error)
+	auditor                       *audit.Auditor
+	auditorOnce                   sync.Once
+	pendingAudit                  pendingAudit
+	breaker                       *circuitbreaker.Breaker
+	breakerOnce                   sync.Once
+	explains                      explains
 }
 
 func appendHandlers(r *mux.Router, b *Backend) *mux.Router {
+	// The health and admin endpoints are registered first, since they
+	// would otherwise match the routes of a type descriptor
+	r.HandleFunc("/healthz", b.Healthz).
+		Name("Healthz").
+		Methods("GET")
+	r.HandleFunc("/readyz", b.Readyz).
+		Name("Readyz").
+		Methods("GET")
+	r.HandleFunc("/admin/reload", b.ReloadConfig).
+		Name("ReloadConfig").
+		Methods("POST")
+	r.HandleFunc("/admin/descriptor-schema", b.GetDescriptorSchema).
+		Name("GetDescriptorSchema").
+		Methods("GET")
+	r.HandleFunc("/openapi.json", b.GetOpenAPI).
+		Name("GetOpenAPI").
+		Methods("GET")
 	r.HandleFunc("/{table}/options/{id}", b.GetSingleAsOption).
 		Name("GetSingleAsOption").
 		Methods("GET")
+	r.HandleFunc("/{table}/{id}/actions/{name}", b.ExecAction).
+		Name("ExecAction").
+		Methods("POST")
 	r.HandleFunc("/{table}/options", b.GetCollectionAsOptions).
 		Name("GetCollectionAsOptions").
 		Methods("GET")
@@ -90,6 +139,33 @@ func (b *Backend) Open(args ...interface{}) error {
 	return b.OpenFunc(args...)
 }
 
+// Close writes the queued audit records and closes the connection to the
+// database. It must only be called once no more requests are handled.
+func (b *Backend) Close() error {
+	// Prevent the auditor from being created after it has been closed
+	b.auditorOnce.Do(func() {})
+	if b.auditor != nil {
+		if err := b.auditor.Close(); err != nil {
+			log.When(config.Logging()).Errorf("[backend] unable to close audit log: %s\n", err)
+		}
+	}
+	// Slow queries must not be explained once the database is closed
+	b.explains.close()
+	if b.CloseFunc == nil {
+		return nil
+	}
+	return b.CloseFunc()
+}
+
+// Reload reads config.yml and descriptor.json again and rebuilds the
+// schema mappings. The current configuration is kept if either is invalid.
+func (b *Backend) Reload() error {
+	if b.ReloadFunc == nil {
+		return fmt.Errorf("backend does not support reloading the configuration")
+	}
+	return b.ReloadFunc()
+}
+
 func (b *Backend) GetCoerceArgFuncs() map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error) {
 	return b.CoerceArgFuncs
 }
@@ -102,22 +178,189 @@ func (b *Backend) GetQueryTemplate(name string) string {
 	return b.GetQueryTemplateFunc(name)
 }
 
+// TableSource returns what the queries reading a table select from, which
+// also contains the computed fields of the table's type descriptor
+func (b *Backend) TableSource(ctx context.Context, table string) (string, error) {
+	if b.TableSourceFunc == nil {
+		return "", fmt.Errorf("backend does not support computed fields")
+	}
+	return b.TableSourceFunc(ctx, table)
+}
+
+// rowFilter returns the row filter of the requested type descriptor with
+// the authenticated principal's attributes bound as arguments, starting
+// at the given bind parameter position
+func (b *Backend) rowFilter(req *http.Request, position int) (string, []interface{}, error) {
+	rowFilter, _ := req.Context().Value(util.ContextKey("rowFilter")).(string)
+	principal, _ := req.Context().Value(util.ContextKey("principal")).(*util.Principal)
+	return query.InterpolateRowFilter(rowFilter, principal, b.FormatPlaceholder, position)
+}
+
+// withRowFilterCheck returns the request with a check in its context, which
+// the backend runs within the transaction of a write operation. The check
+// fails, if the row written with the resource id does not match the row
+// filter of the requested type descriptor. An empty id refers to the row
+// created by the write operation, whose id is returned by the database.
+func (b *Backend) withRowFilterCheck(req *http.Request, id string) (*http.Request, error) {
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, 2)
+	if err != nil || rowFilter == "" {
+		return req, err
+	}
+	queryTemplate := &query.QueryTemplate{
+		Vars: []string{b.GetQueryTemplate("CheckRowFilter")},
+		TemplateData: struct {
+			TableName      string
+			UniqueIdColumn string
+			RowFilter      string
+		}{
+			TableName:      req.Context().Value(util.ContextKey("table")).(string),
+			UniqueIdColumn: req.Context().Value(util.ContextKey("uniqueIDColumn")).(string),
+			RowFilter:      rowFilter,
+		},
+		TableSource:    b.TableSource,
+		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+	}
+	queryString, _, err := queryTemplate.Interpolate(req.Context(), nil)
+	if err != nil {
+		return req, err
+	}
+	var check util.RowFilterCheck = func(ctx context.Context, tx *sql.Tx, result sql.Result) error {
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return nil
+		}
+		var written interface{} = id
+		if id == "" {
+			lastInsertID, err := result.LastInsertId()
+			if err != nil || lastInsertID < 1 {
+				return fmt.Errorf(
+					"unable to check the row filter, since the database does not return the id of created resources: %w",
+					query.ErrRowFilterViolated,
+				)
+			}
+			written = lastInsertID
+		}
+		q, args, err := b.queryParameters(ctx, queryString, append([]interface{}{written}, rowFilterArgs...))
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.QueryRowContext(ctx, q, args...).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return query.ErrRowFilterViolated
+		}
+		return nil
+	}
+	return req.WithContext(context.WithValue(req.Context(), util.ContextKey("rowFilterCheck"), check)), nil
+}
+
+// queryParameters binds the authenticated principal's attributes to the
+// parameters of the SQL queries backing type descriptors, which are
+// selected from by the interpolated query q
+func (b *Backend) queryParameters(ctx context.Context, q string, args []interface{}) (string, []interface{}, error) {
+	principal, _ := ctx.Value(util.ContextKey("principal")).(*util.Principal)
+	return query.InterpolateQueryParameters(q, args, query.PrincipalAttributes(principal), b.FormatPlaceholder)
+}
+
 func (b *Backend) GetSchemaMapping(typeDescriptor string) *descriptor.SchemaMapping {
 	return b.GetSchemaMappingFunc(typeDescriptor)
 }
 
 func (b *Backend) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
-	return b.ExecContextFunc(ctx, query, args...)
+	start := time.Now()
+	defer metrics.ObserveQuery(queryTemplateName(ctx), start)
+	ctx, span := startDBSpan(ctx, "db.exec", query)
+	defer func() { tracing.End(span, err) }()
+	// Writes are not retried, since they might have been applied
+	err = b.call(ctx, false, func() (err error) {
+		result, err = b.ExecContextFunc(ctx, query, args...)
+		return err
+	})
+	var rowsAffected int64
+	if err == nil && result != nil {
+		rowsAffected, _ = result.RowsAffected()
+	}
+	b.logSlowQuery(ctx, query, args, rowsAffected, err, time.Since(start))
+	return result, err
+}
+
+// ExecProcedure calls the stored procedure of action with args, which
+// are the id of the resource followed by the action's parameters. It
+// returns the rows of the procedure's result set, or a single row with
+// its output parameters, keyed by the output parameters of the action.
+func (b *Backend) ExecProcedure(ctx context.Context, action *descriptor.Action, args ...interface{}) (results []map[string]interface{}, err error) {
+	if b.ExecProcedureFunc == nil {
+		return nil, ErrProceduresUnsupported
+	}
+	start := time.Now()
+	defer metrics.ObserveQuery(queryTemplateName(ctx), start)
+	ctx, span := startDBSpan(ctx, "db.exec", action.Procedure)
+	defer func() { tracing.End(span, err) }()
+	// Procedures are not retried, since their changes might have been applied
+	err = b.call(ctx, false, func() (err error) {
+		results, err = b.ExecProcedureFunc(ctx, action, args...)
+		return err
+	})
+	b.logSlowQuery(ctx, action.Procedure, args, int64(len(results)), err, time.Since(start))
+	return results, err
 }
 
 func (b *Backend) QueryContext(ctx context.Context, query string, args ...interface{}) (results []interface{}, err error) {
-	return b.QueryContextFunc(ctx, query, args...)
+	start := time.Now()
+	defer metrics.ObserveQuery(queryTemplateName(ctx), start)
+	query, args, err = b.queryParameters(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startDBSpan(ctx, "db.query", query)
+	defer func() { tracing.End(span, err) }()
+	// Reads within a client transaction are not retried, since the
+	// transaction is usually unusable after a transient error
+	requestTx, _ := ctx.Value(util.ContextKey("tx")).(string)
+	err = b.call(ctx, requestTx == "", func() (err error) {
+		results, err = b.QueryContextFunc(ctx, query, args...)
+		return err
+	})
+	b.logSlowQuery(ctx, query, args, int64(len(results)), err, time.Since(start))
+	return results, err
+}
+
+// startDBSpan starts a client span for a database call. Only the
+// interpolated query with its placeholders is recorded, never the
+// arguments, which may contain personal data.
+func startDBSpan(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return tracing.Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(config.Current(ctx).Database.Driver),
+			semconv.DBStatementKey.String(query),
+			attribute.String("template", queryTemplateName(ctx)),
+		),
+	)
+}
+
+// queryTemplateName returns the name of the query template used by the
+// current route, or `other` for queries not executed by a route handler
+func queryTemplateName(ctx context.Context) string {
+	if routeName, ok := ctx.Value(util.ContextKey("currentRoute")).(string); ok && routeName != "" {
+		return routeName
+	}
+	return "other"
 }
 
 func (b *Backend) CommitTx(txUUID string) (err error) {
-	return b.CommitTxFunc(txUUID)
+	return b.call(context.Background(), false, func() error {
+		return b.CommitTxFunc(txUUID)
+	})
 }
 
 func (b *Backend) CreateTx(timeout time.Duration) (txUUID uuid.UUID, err error) {
-	return b.CreateTxFunc(timeout)
+	err = b.call(context.Background(), true, func() (err error) {
+		txUUID, err = b.CreateTxFunc(timeout)
+		return err
+	})
+	return txUUID, err
 }
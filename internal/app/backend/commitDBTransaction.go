@@ -14,27 +14,22 @@ import (
 func (b *Backend) CommitTransaction(rw http.ResponseWriter, req *http.Request) {
 	requestTx := mux.Vars(req)["commit"]
 	routeName := mux.CurrentRoute(req).GetName()
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 	if err := b.CommitTx(requestTx); err != nil {
+		b.discardAudit(req.Context(), requestTx)
 		if strings.Contains(err.Error(), "404") {
-			msg := &util.ResponseMessage{
-				Code: http.StatusNotFound,
-				Msg: fmt.Sprintf(
-					"transaction %s does not exist in the backend's list of open transactions",
-					requestTx,
-				),
-				Tx: requestTx,
-			}
-			http.Error(rw, msg.String(), http.StatusNotFound)
+			msg := util.NewError(util.ErrorCodeNotFound, fmt.Sprintf(
+				"transaction %s does not exist in the backend's list of open transactions",
+				requestTx,
+			))
+			msg.Tx = requestTx
+			http.Error(rw, msg.String(), msg.Code)
 			return
 		}
-		http.Error(
-			rw,
-			err.Error(),
-			http.StatusInternalServerError,
-		)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
+	b.auditCommit(req, requestTx)
 	msg := &util.ResponseMessage{
 		Code: http.StatusOK,
 		Msg: fmt.Sprintf(
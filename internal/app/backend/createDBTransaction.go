@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -13,17 +14,17 @@ import (
 
 func (b *Backend) CreateTransaction(rw http.ResponseWriter, req *http.Request) {
 	routeName := mux.CurrentRoute(req).GetName()
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
-	txUUID, err := b.CreateTx(60 * time.Second)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
+	timeout := 60 * time.Second
+	txUUID, err := b.CreateTx(timeout)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-			Tx:   txUUID.String(),
-		}
-		http.Error(rw, msg.String(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
+	// The backend deletes the transaction once the timeout expired. The
+	// audit records are discarded in a context keeping the request ID.
+	expiredCtx := log.WithRequestID(context.Background(), log.RequestID(req.Context()))
+	time.AfterFunc(timeout, func() { b.discardAudit(expiredCtx, txUUID.String()) })
 	msg := &util.ResponseMessage{
 		Code: http.StatusInternalServerError,
 		Msg: fmt.Sprintf(
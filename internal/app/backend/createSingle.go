@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
@@ -19,25 +20,27 @@ func (b *Backend) CreateSingle(rw http.ResponseWriter, req *http.Request) {
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	requestData, err := util.ParseDataForm(req)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithError(rw, req, util.ErrorCodeValidationFailed, err.Error(), nil)
 		return
 	}
-	columnNames := getColumnNamesFromRequestData(table, requestData)
+	if computed := getComputedFieldsFromRequestData(req.Context(), table, requestData); len(computed) > 0 {
+		respondWithError(
+			rw, req, util.ErrorCodeValidationFailed,
+			fmt.Sprintf("the computed field(s) %s can not be written", strings.Join(computed, ", ")),
+			nil,
+		)
+		return
+	}
+	columnNames := getColumnNamesFromRequestData(req.Context(), table, requestData)
 	if len(columnNames) == 0 {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg: fmt.Sprintf(
-				"the request data contains *one or more* fields "+
-					"that are not present in the database\n"+
-					"request data:\n%v\n"+
-					"fields available in database table:\n%v\n",
-				requestData, b.GetSchemaMapping(table).FieldNames),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithError(
+			rw, req, util.ErrorCodeValidationFailed,
+			"the request data does not contain any of the fields of the type descriptor",
+			fmt.Errorf(
+				"request data:\n%v\nfields available in database table:\n%v",
+				requestData, b.GetSchemaMapping(table).FieldNames,
+			),
+		)
 		return
 	}
 	queryTemplate := query.QueryTemplate{
@@ -55,41 +58,39 @@ func (b *Backend) CreateSingle(rw http.ResponseWriter, req *http.Request) {
 		CoerceArgFuncs:   b.GetCoerceArgFuncs(),
 		QueryFormatFuncs: b.GetQueryFormatFuncs(),
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler -> backend] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> backend] interpolate query string")
 	queryString, args, err := queryTemplate.Interpolate(req.Context(), requestData)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
+		return
+	}
+	log.When(config.Logging()).WithContext(req.Context()).Debugln(queryString)
+	req, err = b.withRowFilterCheck(req, "")
+	if err != nil {
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infoln(queryString)
 
-	log.When(config.Options.Logging).Infoln("[handler -> db] get query results")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> db] get query results")
 	result, err := b.ExecContext(req.Context(), queryString, args...)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n", result)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n", result)
 
-	log.When(config.Options.Logging).Infoln("[handler] try to return the newly updated resource")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler] try to return the newly updated resource")
 	lastInsertID, err := result.LastInsertId()
 	if err != nil || lastInsertID < 1 {
+		b.audit(req, routeName, "", nil, requestDataByColumn(req.Context(), table, requestData))
 		// LastInsertId() probably not supported by the database. Therefore,
 		// Since we can not return the newly created resource to the user,
 		// we instead return an empty body and a 204 No Content
-		log.When(config.Options.Logging).Infof(
+		log.When(config.Logging()).WithContext(req.Context()).Infof(
 			"[handler] returning newly updated resource not supported by %s database\n",
-			config.Options.Database.Driver,
+			config.Current(req.Context()).Database.Driver,
 		)
 		msg := &util.ResponseMessage{
 			Code: http.StatusNoContent,
@@ -99,6 +100,7 @@ func (b *Backend) CreateSingle(rw http.ResponseWriter, req *http.Request) {
 		rw.Write(msg.Byte())
 		return
 	}
+	b.audit(req, routeName, fmt.Sprintf("%d", lastInsertID), nil, requestDataByColumn(req.Context(), table, requestData))
 	updatedRoute := context.WithValue(
 		req.Context(),
 		util.ContextKey("currentRoute"),
@@ -119,9 +121,9 @@ func (b *Backend) CreateSingle(rw http.ResponseWriter, req *http.Request) {
 	return
 }
 
-func getColumnNamesFromRequestData(tableName string, requestData map[string]interface{}) (columnNames []string) {
+func getColumnNamesFromRequestData(ctx context.Context, tableName string, requestData map[string]interface{}) (columnNames []string) {
 	td := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		tableName,
 	)
 	for _, field := range td.Fields {
@@ -140,3 +142,22 @@ func getColumnNamesFromRequestData(tableName string, requestData map[string]inte
 	}
 	return
 }
+
+// getComputedFieldsFromRequestData returns the keys of the computed fields
+// contained in the request data. Computed fields are read only, since
+// their values are the result of an SQL expression.
+func getComputedFieldsFromRequestData(ctx context.Context, tableName string, requestData map[string]interface{}) (keys []string) {
+	td := util.GetTypeDescriptorUsingDBTableName(
+		config.Current(ctx).Descriptor.TypeDescriptors,
+		tableName,
+	)
+	for _, field := range td.Fields {
+		if len(field.Expression) == 0 {
+			continue
+		}
+		if _, ok := requestData[field.Key]; ok {
+			keys = append(keys, field.Key)
+		}
+	}
+	return
+}
@@ -17,59 +17,53 @@ func (b *Backend) DeleteSingle(rw http.ResponseWriter, req *http.Request) {
 	table := req.Context().Value(util.ContextKey("table")).(string)
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	queryUninterpolated := b.GetQueryTemplate(routeName)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, 2)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
+		return
+	}
 	queryTemplate := &query.QueryTemplate{Vars: []string{queryUninterpolated}, TemplateData: struct {
 		TableName      string
 		UniqueIdColumn string
+		RowFilter      string
 	}{
 		TableName:      table,
 		UniqueIdColumn: uniqueIDColumn,
+		RowFilter:      rowFilter,
 	},
 		CoerceArgFuncs: b.GetCoerceArgFuncs(),
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler -> backend] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> backend] interpolate query string")
 	queryString, _, err := queryTemplate.Interpolate(req.Context(), nil)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- backend]\n%s\n", queryString)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- backend]\n%s\n", queryString)
 
-	log.When(config.Options.Logging).Infoln("[handler -> db] get query results")
-	result, err := b.ExecContext(req.Context(), queryString, id)
+	before := b.currentColumnValues(req, id)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> db] get query results")
+	result, err := b.ExecContext(req.Context(), queryString, append([]interface{}{id}, rowFilterArgs...)...)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n",
 		result,
 	)
 	rowsAffected, err := result.RowsAffected()
 	if err != nil || rowsAffected == 0 {
-		msg := &util.ResponseMessage{
-			Code: http.StatusNotFound,
-			Msg: fmt.Sprintf(
-				"Resource with uniqueID '%s' not found in %s table",
-				id, table,
-			),
-		}
-		http.Error(rw, msg.Error(), http.StatusNotFound)
+		respondWithNotFound(rw, req, id)
 		return
 	}
+	b.audit(req, routeName, id, before, nil)
 	msg := &util.ResponseMessage{
 		Code: http.StatusOK,
 		Msg: fmt.Sprintf(
-			"Resource with uniqueID '%s' successfully deleted from %s table",
-			id, table,
+			"Resource with uniqueID '%s' successfully deleted from %s",
+			id, mux.Vars(req)["table"],
 		),
 	}
 	rw.Write(msg.Byte())
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/circuitbreaker"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// IsConflictError reports whether err is caused by a violated constraint
+// of the database, such as a unique key or a foreign key
+func (b *Backend) IsConflictError(err error) bool {
+	if err == nil || b.IsConflictErrorFunc == nil {
+		return false
+	}
+	return b.IsConflictErrorFunc(err)
+}
+
+// respondWithError answers the request with the given error code. The
+// message is returned to the client, while err, which may contain table
+// names, columns or SQL, is only written to the server log together with
+// the ID of the request.
+func respondWithError(rw http.ResponseWriter, req *http.Request, code util.ErrorCode, msg string, err error) {
+	if err != nil {
+		logger := log.When(config.Logging()).WithContext(req.Context())
+		if code == util.ErrorCodeInternal {
+			logger.Errorf("[handler] %s: %s\n", msg, err)
+		} else {
+			logger.Infof("[handler] %s: %s\n", msg, err)
+		}
+	}
+	if code == util.ErrorCodeInternal {
+		msg = fmt.Sprintf(
+			"%s, please contact your administrator quoting the request ID %s",
+			msg, log.RequestID(req.Context()),
+		)
+	}
+	resp := util.NewError(code, msg)
+	http.Error(rw, resp.Error(), resp.Code)
+}
+
+// respondWithDatabaseError answers the request with the error code that
+// corresponds to err, which was returned by the database
+func (b *Backend) respondWithDatabaseError(rw http.ResponseWriter, req *http.Request, err error) {
+	var missing query.ErrMissingPrincipalAttribute
+	switch {
+	case errors.As(err, &missing):
+		// The query backing the type descriptor has a parameter bound
+		// to an attribute the authenticated user does not have
+		respondWithError(rw, req, util.ErrorCodeForbidden, missing.Error(), nil)
+	case errors.Is(err, query.ErrRowFilterViolated):
+		// The written row was rolled back, together with the client's
+		// transaction it was written in
+		if tx, _ := req.Context().Value(util.ContextKey("tx")).(string); tx != "" {
+			b.discardAudit(req.Context(), tx)
+		}
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
+	case req.Context().Err() == context.DeadlineExceeded:
+		respondWithError(rw, req, util.ErrorCodeTimeout, fmt.Sprintf(
+			"The database did not respond within the statement timeout of %s",
+			config.Current(req.Context()).Database.StatementTimeout,
+		), err)
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		openTimeout := config.Current(req.Context()).Database.CircuitBreaker.OpenTimeout
+		if openTimeout <= 0 {
+			openTimeout = circuitbreaker.DefaultOpenTimeout
+		}
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(openTimeout.Seconds()))))
+		respondWithError(rw, req, util.ErrorCodeDBUnavailable, msgDatabaseUnavailable, err)
+	case b.IsTransientError(err):
+		respondWithError(rw, req, util.ErrorCodeDBUnavailable, msgDatabaseUnavailable, err)
+	case b.IsConflictError(err):
+		respondWithError(rw, req, util.ErrorCodeConflict,
+			"The request conflicts with existing data, for example a duplicate unique ID or a missing related resource", err)
+	default:
+		respondWithError(rw, req, util.ErrorCodeInternal,
+			"An internal error occurred while querying the database", err)
+	}
+}
+
+const msgDatabaseUnavailable = "The database is temporarily unavailable, please retry later"
+
+// respondWithInterpolationError answers the request if the query could not
+// be interpolated, either because the request data is invalid or because
+// the query template is broken
+func respondWithInterpolationError(rw http.ResponseWriter, req *http.Request, err error) {
+	var invalid query.ErrInvalidRequestData
+	if errors.As(err, &invalid) {
+		respondWithError(rw, req, util.ErrorCodeValidationFailed, invalid.Error(), nil)
+		return
+	}
+	respondWithError(rw, req, util.ErrorCodeInternal, "Unable to build the database query", err)
+}
+
+// respondWithNotFound answers the request with 404 Not Found. The resource
+// is identified by the key of its type descriptor, since the name of the
+// database table must not be disclosed.
+func respondWithNotFound(rw http.ResponseWriter, req *http.Request, id string) {
+	respondWithError(rw, req, util.ErrorCodeNotFound, fmt.Sprintf(
+		"Resource with uniqueID '%s' not found in %s",
+		id, mux.Vars(req)["table"],
+	), nil)
+}
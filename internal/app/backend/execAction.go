@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/formatting"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// ExecAction calls the stored procedure of an action of the requested type
+// descriptor for the resource with the given id. The request data contains
+// the input parameters of the action, the response its output parameters
+// or the rows of its result set.
+func (b *Backend) ExecAction(rw http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	name := mux.Vars(req)["name"]
+	routeName := mux.CurrentRoute(req).GetName()
+	table := req.Context().Value(util.ContextKey("table")).(string)
+	td := util.GetTypeDescriptorUsingDBTableName(config.Current(req.Context()).Descriptor.TypeDescriptors, table)
+	action := td.Action(name)
+	if action == nil {
+		respondWithError(rw, req, util.ErrorCodeNotFound, fmt.Sprintf(
+			"The action '%s' does not exist for %s",
+			name, mux.Vars(req)["table"],
+		), nil)
+		return
+	}
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s %s\n", routeName, action.Name)
+
+	requestData := make(map[string]interface{})
+	if len(action.Parameters) > 0 {
+		var err error
+		requestData, err = util.ParseDataForm(req)
+		if err != nil {
+			respondWithError(rw, req, util.ErrorCodeValidationFailed, err.Error(), nil)
+			return
+		}
+	}
+	args := []interface{}{id}
+	for _, parameter := range action.Parameters {
+		value, ok, err := query.CoerceArg(
+			requestData,
+			&descriptor.Field{Key: parameter.Key, Type: parameter.Type},
+			b.GetCoerceArgFuncs(),
+		)
+		if err != nil {
+			respondWithError(rw, req, util.ErrorCodeValidationFailed,
+				query.ErrInvalidRequestData{Err: err}.Error(), nil)
+			return
+		}
+		if !ok {
+			value = nil
+		}
+		args = append(args, value)
+	}
+
+	// The procedure is only called for resources the user is allowed to
+	// read, since it is not restricted by the row filter itself
+	current, err := b.columnValues(req, id)
+	var missing query.ErrMissingPrincipalAttribute
+	if errors.As(err, &missing) {
+		respondWithError(rw, req, util.ErrorCodeForbidden, missing.Error(), nil)
+		return
+	}
+	if err != nil {
+		b.respondWithDatabaseError(rw, req, err)
+		return
+	}
+	if current == nil {
+		respondWithNotFound(rw, req, id)
+		return
+	}
+
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler -> db] call procedure %s\n", action.Procedure)
+	results, err := b.ExecProcedure(req.Context(), action, args...)
+	if errors.Is(err, ErrProceduresUnsupported) {
+		respondWithError(rw, req, util.ErrorCodeNotImplemented, fmt.Sprintf(
+			"The %s database does not support actions",
+			config.Current(req.Context()).Database.Driver,
+		), nil)
+		return
+	}
+	if err != nil {
+		b.respondWithDatabaseError(rw, req, err)
+		return
+	}
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] procedure results: \n%s\n", results)
+	b.audit(req, fmt.Sprintf("%s/%s", routeName, action.Name), id, nil, nil)
+
+	formattedResults, err := formatting.FormatActionResults(req.Context(), action, results)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to format the results of the action", err)
+		return
+	}
+	rw.Write(formattedResults)
+}
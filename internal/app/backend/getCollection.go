@@ -21,15 +21,16 @@ func (b *Backend) GetCollection(rw http.ResponseWriter, req *http.Request) {
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	requestData, err := util.ParseDataForm(req)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeValidationFailed, err.Error(), nil)
+		return
+	}
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler] requestData: \n%s", requestData)
+	columnNames := util.GetColumnNamesFromRequestData(req.Context(), table, requestData)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, len(columnNames)+1)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler] requestData: \n%s", requestData)
-	columnNames := util.GetColumnNamesFromRequestData(table, requestData)
 	queryTemplate := &query.QueryTemplate{
 		Vars: []string{queryUninterpolated},
 		TemplateData: struct {
@@ -37,55 +38,46 @@ func (b *Backend) GetCollection(rw http.ResponseWriter, req *http.Request) {
 			Relations      []*descriptor.Field
 			UniqueIdColumn string
 			ColumnNames []string
+			RowFilter      string
 		}{
 			TableName:      table,
 			Relations:      relations,
 			UniqueIdColumn: uniqueIDColumn,
 			ColumnNames: columnNames,
+			RowFilter:      rowFilter,
 		},
 		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+		TableSource:    b.TableSource,
 	}
 
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler] interpolate query string")
 	queryString, args, err := queryTemplate.Interpolate(req.Context(), requestData)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
 		return
 	}
 
-	log.When(config.Options.Logging).Infoln(queryString)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln(queryString)
 
-	log.When(config.Options.Logging).Infoln("[handler -> db] get query results")
-	results, err := b.QueryContext(req.Context(), queryString, args...)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> db] get query results")
+	results, err := b.QueryContext(req.Context(), queryString, append(args, rowFilterArgs...)...)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n",
 		results,
 	)
 
-	log.When(config.Options.Logging).Infoln("[handler -> Format] format results as json")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> Format] format results as json")
 	formattedResults, err := formatting.Collection.Format(req.Context(), results)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to format the query results", err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- formatter] formatted results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- formatter] formatted results: \n%s\n",
 		formattedResults,
 	)
 
@@ -20,14 +20,10 @@ func (b *Backend) GetCollectionAsOptions(rw http.ResponseWriter, req *http.Reque
 	var args []interface{}
 	requestData, err := util.ParseDataForm(req)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithError(rw, req, util.ErrorCodeValidationFailed, err.Error(), nil)
 		return
 	}
-	columnNames := util.GetColumnNamesFromRequestData(table, requestData)
+	columnNames := util.GetColumnNamesFromRequestData(req.Context(), table, requestData)
 	// Interpolate to `LIKE '%'` in query string when filter parameter
 	// not provided by client
 	filter := "%"
@@ -35,6 +31,11 @@ func (b *Backend) GetCollectionAsOptions(rw http.ResponseWriter, req *http.Reque
 		filter = fmt.Sprintf("%%%s%%", value)
 	}
 	queryUninterpolated := b.GetQueryTemplate(routeName)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, len(columnNames)+2)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
+		return
+	}
 	queryTemplate := &query.QueryTemplate{
 		Vars: []string{queryUninterpolated},
 		TemplateData: struct {
@@ -42,28 +43,28 @@ func (b *Backend) GetCollectionAsOptions(rw http.ResponseWriter, req *http.Reque
 			UniqueIdColumn     string
 			ColumnAsOptionName string
 			ColumnNames []string
+			RowFilter          string
 		}{
 			TableName:          table,
 			UniqueIdColumn:     uniqueIDColumn,
 			ColumnAsOptionName: columnAsOptionName,
 			ColumnNames: columnNames,
+			RowFilter:          rowFilter,
 		},
 		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+		TableSource:    b.TableSource,
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
-log.When(config.Options.Logging).Infoln("[handler -> backend] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
+log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> backend] interpolate query string")
 	queryString, args, err := queryTemplate.Interpolate(req.Context(), requestData)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- backend]\n%s\n", queryString)
+	args = append(args, rowFilterArgs...)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- backend]\n%s\n", queryString)
 
-	log.When(config.Options.Logging).Infof(
+	log.When(config.Logging()).WithContext(req.Context()).Debugf(
 		"[handler -> db] get query results using\nquery string:\n%s"+
 			"\nwith the following args:\n%s\n",
 		queryString,
@@ -71,28 +72,20 @@ log.When(config.Options.Logging).Infoln("[handler -> backend] interpolate query
 	)
 	results, err := b.QueryContext(req.Context(), queryString, append([]interface{}{filter}, args...)...)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n",
 		results,
 	)
 
-	log.When(config.Options.Logging).Infoln("[handler -> formatter] format results as json")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> formatter] format results as json")
 	formattedResults, err := formatting.GetCollectionAsOptionsFilterable.Format(req.Context(), results)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to format the query results", err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- formatter] formatted results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- formatter] formatted results: \n%s\n",
 		formattedResults,
 	)
 
@@ -10,14 +10,10 @@ import (
 )
 
 func (b *Backend) GetDescriptorFile(rw http.ResponseWriter, req *http.Request) {
-	log.When(config.Options.Logging).Infoln("[request -> http.ServeFile] descriptor file")
-	descriptor, err := json.MarshalIndent(&config.Options.Descriptor, "", "  ")
+	log.When(config.Logging()).WithContext(req.Context()).Infoln("[request -> http.ServeFile] descriptor file")
+	descriptor, err := json.MarshalIndent(config.Current(req.Context()).Descriptor, "", "  ")
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to encode the descriptor file", err)
 		return
 	}
 	rw.Write(descriptor)
@@ -0,0 +1,17 @@
+package backend
+
+import (
+	"net/http"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+// GetDescriptorSchema returns the JSON Schema that descriptor.json files
+// are validated against, so that editors can validate them as well
+func (b *Backend) GetDescriptorSchema(rw http.ResponseWriter, req *http.Request) {
+	log.When(config.Logging()).WithContext(req.Context()).Infoln("[handler] descriptor schema")
+	rw.Header().Set("Content-Type", "application/schema+json")
+	rw.Write([]byte(descriptor.Schema))
+}
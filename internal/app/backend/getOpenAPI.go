@@ -0,0 +1,23 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/openapi"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// GetOpenAPI returns the OpenAPI specification generated from the current
+// descriptor
+func (b *Backend) GetOpenAPI(rw http.ResponseWriter, req *http.Request) {
+	log.When(config.Logging()).WithContext(req.Context()).Infoln("[handler] openapi specification")
+	spec, err := json.MarshalIndent(openapi.Generate(*config.Current(req.Context())), "", "  ")
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to encode the OpenAPI specification", err)
+		return
+	}
+	rw.Write(spec)
+}
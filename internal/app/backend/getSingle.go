@@ -19,69 +19,56 @@ func (b *Backend) GetSingle(rw http.ResponseWriter, req *http.Request) {
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	queryUninterpolated := b.GetQueryTemplate(routeName)
 	relations := req.Context().Value(util.ContextKey("relationships")).([]*descriptor.Field)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, 2)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
+		return
+	}
 	queryTemplate := &query.QueryTemplate{
 		Vars: []string{queryUninterpolated},
 		TemplateData: struct {
 			TableName      string
 			Relations      []*descriptor.Field
 			UniqueIdColumn string
+			RowFilter      string
 		}{
 			TableName:      table,
 			Relations:      relations,
 			UniqueIdColumn: uniqueIDColumn,
+			RowFilter:      rowFilter,
 		},
 		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+		TableSource:    b.TableSource,
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler] interpolate query string")
 	queryString, _, err := queryTemplate.Interpolate(req.Context(), nil)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infoln(queryString)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln(queryString)
 
-	log.When(config.Options.Logging).Infoln("[handler -> db] get query results")
-	results, err := b.QueryContext(req.Context(), queryString, id)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> db] get query results")
+	results, err := b.QueryContext(req.Context(), queryString, append([]interface{}{id}, rowFilterArgs...)...)
 	if err != nil {
-		switch err.(type) {
-		case *util.ResponseMessage:
-			http.Error(rw, err.Error(), err.(*util.ResponseMessage).Code)
-			return
-		default:
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
-			return
-		}
+		b.respondWithDatabaseError(rw, req, err)
+		return
 	}
 	if len(results) == 0 {
-		msg := &util.ResponseMessage{
-			Code: http.StatusNotFound,
-			Msg: fmt.Sprintf(
-				"Resource with uniqueID '%s' not found in %s table",
-				id, table,
-			),
-		}
-		http.Error(rw, msg.Error(), http.StatusNotFound)
+		respondWithNotFound(rw, req, id)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n", results)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n", results)
 
-	log.When(config.Options.Logging).Infoln("[handler -> formatter] format results as json")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> formatter] format results as json")
 	formattedResults, err := formatting.Standard.Format(req.Context(), results)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to format the query results", err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- formatter] formatted results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- formatter] formatted results: \n%s\n",
 		formattedResults,
 	)
 	isCreated, ok := req.Context().Value(util.ContextKey("isCreated")).(bool)
@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -13,75 +12,64 @@ import (
 )
 
 func (b *Backend) GetSingleAsOption(rw http.ResponseWriter, req *http.Request) {
-	log.When(config.Options.Logging).Infoln("[handler] GetSingleAsOption")
+	log.When(config.Logging()).WithContext(req.Context()).Infoln("[handler] GetSingleAsOption")
 	routeName := mux.CurrentRoute(req).GetName()
 	id := mux.Vars(req)["id"]
 	table := req.Context().Value(util.ContextKey("table")).(string)
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	columnAsOptionName := req.Context().Value(util.ContextKey("columnAsOptionName")).(string)
 	queryUninterpolated := b.GetQueryTemplate(routeName)
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, 2)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
+		return
+	}
 	queryTemplate := &query.QueryTemplate{
 		Vars: []string{queryUninterpolated},
 		TemplateData: struct {
 			TableName          string
 			UniqueIdColumn     string
 			ColumnAsOptionName string
+			RowFilter          string
 		}{
 			TableName:          table,
 			UniqueIdColumn:     uniqueIDColumn,
 			ColumnAsOptionName: columnAsOptionName,
+			RowFilter:          rowFilter,
 		},
 		CoerceArgFuncs: b.GetCoerceArgFuncs(),
+		TableSource:    b.TableSource,
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler -> backend] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> backend] interpolate query string")
 	queryString, _, err := queryTemplate.Interpolate(req.Context(), nil)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- backend]\n%s\n", queryString)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- backend]\n%s\n", queryString)
 
-	log.When(config.Options.Logging).Infoln("[handler -> db] get query results")
-	results, err := b.QueryContext(req.Context(), queryString, id)
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> db] get query results")
+	results, err := b.QueryContext(req.Context(), queryString, append([]interface{}{id}, rowFilterArgs...)...)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n",
 		results,
 	)
 	if len(results) == 0 {
-		msg := &util.ResponseMessage{
-			Code: http.StatusNotFound,
-			Msg: fmt.Sprintf(
-				"Resource with uniqueID '%s' not found in %s table",
-				id, table,
-			),
-		}
-		http.Error(rw, msg.Error(), http.StatusNotFound)
+		respondWithNotFound(rw, req, id)
 		return
 	}
-	log.When(config.Options.Logging).Infoln("[handler -> formatter] format results as json")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> formatter] format results as json")
 	formattedResults, err := formatting.GetSingleAsOption.Format(req.Context(), results)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to format the query results", err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- formatter] formatted results: \n%s\n",
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- formatter] formatted results: \n%s\n",
 		formattedResults,
 	)
 
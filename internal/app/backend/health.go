@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// readinessTimeout limits the time taken by all readiness checks
+const readinessTimeout = 5 * time.Second
+
+// healthCheck is a single check performed by a health endpoint
+type healthCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// HealthStatus is the response of the health endpoints
+type HealthStatus struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single health check. The endpoints do
+// not require authentication, so the error of a failed check is only
+// logged, while Error refers to the log entry by its request ID.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Healthz reports whether the workflow connector is alive and able to
+// handle requests
+func (b *Backend) Healthz(rw http.ResponseWriter, req *http.Request) {
+	writeHealthStatus(rw, req, runHealthChecks(req.Context(), nil))
+}
+
+// Readyz reports whether the workflow connector is ready to handle
+// requests, i.e. whether the database is reachable and the schema of
+// every table in the descriptor has been loaded
+func (b *Backend) Readyz(rw http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), readinessTimeout)
+	defer cancel()
+	writeHealthStatus(rw, req, runHealthChecks(ctx, b.readinessChecks()))
+}
+
+func (b *Backend) readinessChecks() []*healthCheck {
+	checks := []*healthCheck{
+		{name: "database", check: b.Ping},
+		{name: "schemaMappings", check: b.checkSchemaMappings},
+	}
+	if b.Breaker() != nil {
+		checks = append(checks, &healthCheck{name: "circuitBreaker", check: b.checkCircuitBreaker})
+	}
+	return checks
+}
+
+// Ping verifies that the connection to the database is alive
+func (b *Backend) Ping(ctx context.Context) error {
+	if b.PingFunc == nil {
+		return fmt.Errorf("backend does not support pinging the database")
+	}
+	return b.PingFunc(ctx)
+}
+
+// checkSchemaMappings verifies that the schema of every table used by the
+// type descriptors, including the schema of related tables, was loaded
+func (b *Backend) checkSchemaMappings(ctx context.Context) error {
+	for _, table := range config.Current(ctx).Database.Tables {
+		if b.GetSchemaMapping(table.Name) == nil {
+			return fmt.Errorf("schema of table %s is not loaded", table.Name)
+		}
+		if util.TableHasRelationships(*config.Current(ctx), table.Name) &&
+			b.GetSchemaMapping(fmt.Sprintf("%s\x00relationships", table.Name)) == nil {
+			return fmt.Errorf("schema of table %s with relationships is not loaded", table.Name)
+		}
+	}
+	return nil
+}
+
+func runHealthChecks(ctx context.Context, checks []*healthCheck) *HealthStatus {
+	status := &HealthStatus{Status: "ok"}
+	if len(checks) > 0 {
+		status.Checks = make(map[string]*CheckResult)
+	}
+	for _, c := range checks {
+		start := time.Now()
+		err := c.check(ctx)
+		result := &CheckResult{
+			Status:    "ok",
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			log.When(config.Logging()).WithContext(ctx).Warnf(
+				"[handler] health check %s failed: %s\n", c.name, config.Redact(err.Error()),
+			)
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("check failed, see the log for request ID %s", log.RequestID(ctx))
+			status.Status = "failed"
+		}
+		status.Checks[c.name] = result
+	}
+	return status
+}
+
+func writeHealthStatus(rw http.ResponseWriter, req *http.Request, status *HealthStatus) {
+	body, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeInternal, "Unable to encode the health status", err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	if status.Status != "ok" {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	rw.Write(body)
+}
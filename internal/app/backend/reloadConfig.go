@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// ReloadConfig reloads config.yml and descriptor.json without restarting
+// the workflow connector. If either file is invalid, the current
+// configuration is kept and the error is returned to the client. Only
+// admins, see config.Auth.IsAdmin, may reload the configuration.
+func (b *Backend) ReloadConfig(rw http.ResponseWriter, req *http.Request) {
+	routeName := mux.CurrentRoute(req).GetName()
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
+	var username string
+	if principal, ok := req.Context().Value(util.ContextKey("principal")).(*util.Principal); ok {
+		username = principal.Username
+	}
+	if !config.Current(req.Context()).Auth.IsAdmin(username) {
+		respondWithError(
+			rw, req, util.ErrorCodeForbidden,
+			"Only admins may reload the configuration",
+			fmt.Errorf("user '%s' is not an admin", username),
+		)
+		return
+	}
+	if err := b.Reload(); err != nil {
+		log.When(config.Logging()).WithContext(req.Context()).Errorf(
+			"[handler] unable to reload config, keeping current config: %s\n", err,
+		)
+		msg := &util.ResponseMessage{
+			Code: http.StatusUnprocessableEntity,
+			Msg:  err.Error(),
+		}
+		http.Error(rw, msg.String(), http.StatusUnprocessableEntity)
+		return
+	}
+	msg := &util.ResponseMessage{
+		Code: http.StatusOK,
+		Msg:  "configuration successfully reloaded",
+	}
+	rw.Write(msg.Byte())
+}
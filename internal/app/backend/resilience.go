@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/circuitbreaker"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 100 * time.Millisecond
+	defaultRetryMaxDelay    = 2 * time.Second
+)
+
+// IsTransientError reports whether err is a transient database error, such
+// as a refused connection, a serialization failure or a deadlock, that may
+// not occur again when the call is retried
+func (b *Backend) IsTransientError(err error) bool {
+	if err == nil || b.IsTransientErrorFunc == nil {
+		return false
+	}
+	return b.IsTransientErrorFunc(err)
+}
+
+// Breaker returns the circuit breaker configured in the `database` section
+// of the config file, or nil if the circuit breaker is disabled. The
+// circuit breaker is created once, so changes to its options require a
+// restart.
+func (b *Backend) Breaker() *circuitbreaker.Breaker {
+	b.breakerOnce.Do(func() {
+		config.ReloadLock.RLock()
+		cfg := config.Options.Database.CircuitBreaker
+		config.ReloadLock.RUnlock()
+		if cfg.Enabled {
+			b.breaker = circuitbreaker.New(cfg.FailureThreshold, cfg.OpenTimeout)
+		}
+	})
+	return b.breaker
+}
+
+// call calls the database using fn, guarded by the circuit breaker. Calls
+// that are safe to repeat are retried with exponential backoff while they
+// fail with a transient error.
+func (b *Backend) call(ctx context.Context, idempotent bool, fn func() error) error {
+	cfg := config.Current(ctx).Database.Retry
+	attempts := 1
+	if idempotent {
+		attempts = cfg.MaxAttempts
+		if attempts <= 0 {
+			attempts = defaultRetryMaxAttempts
+		}
+	}
+	delay, maxDelay := cfg.BaseDelay, cfg.MaxDelay
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	for attempt := 1; ; attempt++ {
+		err := b.callOnce(fn)
+		if err == nil || attempt >= attempts || !b.IsTransientError(err) {
+			return err
+		}
+		log.When(config.Logging()).WithContext(ctx).Warnf(
+			"[backend] transient database error, retrying in %s (attempt %d of %d): %s\n",
+			delay, attempt, attempts, err,
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = time.Duration(math.Min(float64(2*delay), float64(maxDelay)))
+	}
+}
+
+func (b *Backend) callOnce(fn func() error) error {
+	breaker := b.Breaker()
+	if breaker == nil {
+		return fn()
+	}
+	if err := breaker.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	switch {
+	case err == nil:
+		breaker.Success()
+	case b.IsTransientError(err):
+		breaker.Failure()
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The request was cancelled or timed out, which does not tell
+		// whether the database is available
+		breaker.Ignore()
+	default:
+		// The database answered, albeit with an error
+		breaker.Success()
+	}
+	return err
+}
+
+// checkCircuitBreaker fails while the circuit breaker is not closed
+func (b *Backend) checkCircuitBreaker(ctx context.Context) error {
+	if state := b.Breaker().State(); state != circuitbreaker.Closed {
+		return fmt.Errorf("circuit breaker is %s", state)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// explainTimeout limits the time the database may take to explain a query
+const explainTimeout = 10 * time.Second
+
+// Explain returns the query plan the database uses to execute query
+func (b *Backend) Explain(ctx context.Context, query string, args ...interface{}) (string, error) {
+	if b.ExplainFunc == nil {
+		return "", fmt.Errorf("%s database does not support explaining queries", config.Current(ctx).Database.Driver)
+	}
+	return b.ExplainFunc(ctx, query, args...)
+}
+
+// logSlowQuery logs query if it took longer than the threshold of the slow
+// query log. The query plan of a sample of slow queries is logged as well.
+func (b *Backend) logSlowQuery(ctx context.Context, query string, args []interface{}, rows int64, err error, duration time.Duration) {
+	cfg := config.Current(ctx).Database.SlowQueryLog
+	if cfg.Threshold <= 0 || duration < cfg.Threshold {
+		return
+	}
+	outcome := fmt.Sprintf("rows=%d", rows)
+	if err != nil {
+		outcome = fmt.Sprintf("error=%q", err)
+	}
+	log.When(config.Logging()).WithContext(ctx).Warnf(
+		"[backend] slow query took %s: route=%s typeDescriptor=%s %s args=%s\n%s\n",
+		duration, queryTemplateName(ctx), typeDescriptorKey(ctx), outcome, redactArgs(args), query,
+	)
+	if b.ExplainFunc == nil || cfg.ExplainSampleRate <= 0 || rand.Float64() >= cfg.ExplainSampleRate {
+		return
+	}
+	// The query is explained in the background, since the request has
+	// already waited long enough. The explain context keeps the request ID
+	// and the options but not the deadline of the request.
+	explainCtx, ok := b.explains.start()
+	if !ok {
+		return
+	}
+	explainCtx = log.WithRequestID(explainCtx, log.RequestID(ctx))
+	explainCtx = config.WithOptions(explainCtx, config.Current(ctx))
+	go func() {
+		defer b.explains.done()
+		explainCtx, cancel := context.WithTimeout(explainCtx, explainTimeout)
+		defer cancel()
+		plan, err := b.Explain(explainCtx, query, args...)
+		if err != nil {
+			log.When(config.Logging()).WithContext(explainCtx).Warnf(
+				"[backend] unable to explain slow query: %s\n", err,
+			)
+			return
+		}
+		log.When(config.Logging()).WithContext(explainCtx).Warnf(
+			"[backend] query plan of slow query:\n%s\n%s\n", query, plan,
+		)
+	}()
+}
+
+// explains tracks the slow queries that are explained in the background,
+// so that they can be cancelled and waited for when the backend is closed
+type explains struct {
+	sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// start registers an explain and returns the context it runs in. It
+// reports false once the backend is closed.
+func (e *explains) start() (context.Context, bool) {
+	e.Lock()
+	defer e.Unlock()
+	if e.closed {
+		return nil, false
+	}
+	if e.ctx == nil {
+		e.ctx, e.cancel = context.WithCancel(context.Background())
+	}
+	e.wg.Add(1)
+	return e.ctx, true
+}
+
+// done unregisters an explain registered by start
+func (e *explains) done() {
+	e.wg.Done()
+}
+
+// close cancels the running explains and waits for them to return
+func (e *explains) close() {
+	e.Lock()
+	e.closed = true
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.Unlock()
+	e.wg.Wait()
+}
+
+// typeDescriptorKey returns the key of the type descriptor whose table is
+// queried, or `other` for queries not executed by a route handler
+func typeDescriptorKey(ctx context.Context) string {
+	table, ok := ctx.Value(util.ContextKey("table")).(string)
+	if !ok || table == "" {
+		return "other"
+	}
+	td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, table)
+	if td == nil {
+		return "other"
+	}
+	return td.Key
+}
+
+// redactArgs formats the bind arguments of a query for the log. Any
+// value may contain personal data, such as birth dates or amounts, so only
+// the type of the arguments and the length of strings and byte slices are
+// logged.
+func redactArgs(args []interface{}) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			formatted[i] = fmt.Sprintf("string(len=%d)", len(v))
+		case []byte:
+			formatted[i] = fmt.Sprintf("[]byte(len=%d)", len(v))
+		case nil:
+			formatted[i] = "NULL"
+		default:
+			formatted[i] = fmt.Sprintf("%T", v)
+		}
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
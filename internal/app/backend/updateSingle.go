@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
@@ -21,25 +22,32 @@ func (b *Backend) UpdateSingle(rw http.ResponseWriter, req *http.Request) {
 	uniqueIDColumn := req.Context().Value(util.ContextKey("uniqueIDColumn")).(string)
 	requestData, err := util.ParseDataForm(req)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithError(rw, req, util.ErrorCodeValidationFailed, err.Error(), nil)
 		return
 	}
-	columnNames := getColumnNamesFromRequestData(table, requestData)
+	if computed := getComputedFieldsFromRequestData(req.Context(), table, requestData); len(computed) > 0 {
+		respondWithError(
+			rw, req, util.ErrorCodeValidationFailed,
+			fmt.Sprintf("the computed field(s) %s can not be written", strings.Join(computed, ", ")),
+			nil,
+		)
+		return
+	}
+	columnNames := getColumnNamesFromRequestData(req.Context(), table, requestData)
 	if len(columnNames) == 0 {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg: fmt.Sprintf(
-				"the request data contains *one or more* fields "+
-					"that are not present in the database\n"+
-					"request data:\n%v\n"+
-					"fields available in database table:\n%v\n",
-				requestData, b.GetSchemaMapping(table).FieldNames),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithError(
+			rw, req, util.ErrorCodeValidationFailed,
+			"the request data does not contain any of the fields of the type descriptor",
+			fmt.Errorf(
+				"request data:\n%v\nfields available in database table:\n%v",
+				requestData, b.GetSchemaMapping(table).FieldNames,
+			),
+		)
+		return
+	}
+	rowFilter, rowFilterArgs, err := b.rowFilter(req, len(columnNames)+2)
+	if err != nil {
+		respondWithError(rw, req, util.ErrorCodeForbidden, err.Error(), nil)
 		return
 	}
 	queryTemplate := &query.QueryTemplate{
@@ -48,55 +56,51 @@ func (b *Backend) UpdateSingle(rw http.ResponseWriter, req *http.Request) {
 			TableName      string
 			ColumnNames    []string
 			UniqueIdColumn string
+			RowFilter      string
 		}{
 			TableName:      table,
 			ColumnNames:    columnNames,
 			UniqueIdColumn: uniqueIDColumn,
+			RowFilter:      rowFilter,
 		},
 		ColumnNames:      columnNames,
 		CoerceArgFuncs:   b.GetCoerceArgFuncs(),
 		QueryFormatFuncs: b.GetQueryFormatFuncs(),
 	}
-	log.When(config.Options.Logging).Infof("[handler] %s\n", routeName)
+	log.When(config.Logging()).WithContext(req.Context()).Infof("[handler] %s\n", routeName)
 
-	log.When(config.Options.Logging).Infoln("[handler -> query] interpolate query string")
+	log.When(config.Logging()).WithContext(req.Context()).Debugln("[handler -> query] interpolate query string")
 	queryString, args, err := queryTemplate.Interpolate(req.Context(), requestData)
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusBadRequest,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusBadRequest)
+		respondWithInterpolationError(rw, req, err)
+		return
+	}
+	req, err = b.withRowFilterCheck(req, id)
+	if err != nil {
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
 
-	log.When(config.Options.Logging).Infof(
+	before := b.currentColumnValues(req, id)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf(
 		"[handler -> db] get query results using\nquery string:\n%s"+
 			"\nwith the following args:\n%s\n",
 		queryString,
-		append(args, id),
+		append(append(args, id), rowFilterArgs...),
 	)
-	result, err := b.ExecContext(req.Context(), queryString, append(args, id)...)
+	result, err := b.ExecContext(req.Context(), queryString, append(append(args, id), rowFilterArgs...)...)
 	if err == sql.ErrNoRows {
-		msg := &util.ResponseMessage{
-			Code: http.StatusNotFound,
-			Msg: fmt.Sprintf(
-				"Resource with uniqueID '%s' not found in %s table",
-				id, table,
-			),
-		}
-		http.Error(rw, msg.Error(), http.StatusNotFound)
+		respondWithNotFound(rw, req, id)
 		return
 	}
 	if err != nil {
-		msg := &util.ResponseMessage{
-			Code: http.StatusInternalServerError,
-			Msg:  err.Error(),
-		}
-		http.Error(rw, msg.Error(), http.StatusInternalServerError)
+		b.respondWithDatabaseError(rw, req, err)
 		return
 	}
-	log.When(config.Options.Logging).Infof("[handler <- db] query results: \n%s\n", result)
+	log.When(config.Logging()).WithContext(req.Context()).Debugf("[handler <- db] query results: \n%s\n", result)
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected > 0 {
+		b.audit(req, routeName, id, before, requestDataByColumn(req.Context(), table, requestData))
+	}
 
 	withUpdatedRoute := context.WithValue(
 		req.Context(),
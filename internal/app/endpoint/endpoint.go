@@ -28,6 +28,12 @@ type Endpoint interface {
 	// the functionality required by the CRUD and WorkflowConnector interfaces
 	GetHandler() http.Handler
 	Open(...interface{}) error
+	// Close releases the resources held by the endpoint, such as the
+	// connection to the database, once no more requests are handled
+	Close() error
+	// Reload reads the configuration again and swaps it in, keeping the
+	// current configuration if the new one is invalid
+	Reload() error
 }
 
 // CRUD abstracts the functionality expected from a standard CRUD service,
@@ -53,6 +59,7 @@ type Backend interface {
 	GetQueryTemplate(string) string
 	QueryContext(context.Context, string, ...interface{}) ([]interface{}, error)
 	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	ExecProcedure(context.Context, *descriptor.Action, ...interface{}) ([]map[string]interface{}, error)
 	CommitTx(string) error
 	CreateTx(time.Duration) (uuid.UUID, error)
 }
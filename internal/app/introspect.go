@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+	"github.com/signavio/workflow-connector/internal/pkg/sql/mysql"
+	"github.com/signavio/workflow-connector/internal/pkg/sql/oracle"
+	"github.com/signavio/workflow-connector/internal/pkg/sql/postgres"
+	"github.com/signavio/workflow-connector/internal/pkg/sql/sqlite"
+	"github.com/signavio/workflow-connector/internal/pkg/sql/sqlserver"
+)
+
+// NewIntrospector returns the function that reads the schema of the
+// database of the configured driver
+func NewIntrospector(cfg config.Config) (introspect.Func, error) {
+	switch cfg.Database.Driver {
+	case "sqlserver":
+		return sqlserver.Introspect, nil
+	case "sqlite3":
+		return sqlite.Introspect, nil
+	case "mysql":
+		return mysql.Introspect, nil
+	case "postgres":
+		return postgres.Introspect, nil
+	case "godror":
+		return oracle.Introspect, nil
+	default:
+		return nil, fmt.Errorf("Database driver: %s, not supported", cfg.Database.Driver)
+	}
+}
+
+// Introspect implements the `introspect` command. It reads the schema of
+// the configured database and writes the descriptor of the config
+// directory, merged with type descriptors for the tables of the database,
+// to stdout or to the file given with `-output`.
+func Introspect(cfg config.Config, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("introspect", flag.ContinueOnError)
+	tables := flags.String("tables", "", "comma separated list of tables to introspect, defaults to all tables")
+	output := flags.String("output", "", "write the descriptor to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	schema, err := readSchema(cfg)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if *tables != "" {
+		for _, name := range strings.Split(*tables, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	schema, err = introspect.Filter(schema, names)
+	if err != nil {
+		return err
+	}
+	d := cfg.Descriptor
+	if d == nil || d.Key == "" {
+		d = &descriptor.Descriptor{
+			Key:             strings.Replace(strings.ToLower(cfg.Name), " ", "-", -1),
+			Name:            cfg.DisplayName,
+			Description:     cfg.Description,
+			Version:         1,
+			ProtocolVersion: 1,
+		}
+		if cfg.Descriptor != nil {
+			d.TypeDescriptors = cfg.Descriptor.TypeDescriptors
+		}
+	}
+	content, err := json.MarshalIndent(introspect.Merge(d, schema), "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to marshal descriptor.json: %v", err)
+	}
+	// Make sure the generated descriptor is accepted on startup
+	if _, err := descriptor.ParseDescriptorFile(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("Generated descriptor is invalid: %v", err)
+	}
+	content = append(content, '\n')
+	if *output == "" {
+		_, err = stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(*output, content, 0644)
+}
+
+// readSchema reads the schema of the configured database
+func readSchema(cfg config.Config) ([]*introspect.Table, error) {
+	introspector, err := NewIntrospector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(cfg.Database.Driver, cfg.Database.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open database: %v", err)
+	}
+	defer db.Close()
+	tables, err := introspector(context.Background(), db)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to introspect database: %v", err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/openapi"
+)
+
+// OpenAPI implements the `openapi` command. It writes the OpenAPI
+// specification generated from the descriptor to stdout or to the file
+// given with `-output`.
+func OpenAPI(cfg config.Config, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	output := flags.String("output", "", "write the specification to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	spec, err := json.MarshalIndent(openapi.Generate(cfg), "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to marshal OpenAPI specification: %v", err)
+	}
+	spec = append(spec, '\n')
+	if *output == "" {
+		_, err = stdout.Write(spec)
+		return err
+	}
+	return ioutil.WriteFile(*output, spec, 0644)
+}
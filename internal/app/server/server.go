@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
 	"github.com/signavio/workflow-connector/internal/pkg/middleware"
 	"github.com/urfave/negroni"
 )
@@ -23,6 +24,12 @@ func NewServer(cfg config.Config, e endpoint.Endpoint) *http.Server {
 	// TODO this is cheesy that we are using negroni only for its
 	// built in NewRecovery and NewLogger middlewares
 	n := negroni.New(negroni.NewRecovery(), negroni.NewLogger())
+	router.Use(middleware.ReloadGuard)
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Tracing)
+	router.Use(middleware.Metrics)
+	router.Use(middleware.IPAllowlist)
+	router.Use(middleware.RateLimit)
 	router.Use(middleware.BasicAuth)
 	router.Use(middleware.RouteChecker)
 	router.Use(middleware.RequestInjector)
@@ -33,6 +40,25 @@ func NewServer(cfg config.Config, e endpoint.Endpoint) *http.Server {
 	return server
 }
 
+// NewMetricsServer returns a http server exposing Prometheus metrics at
+// `/metrics` on the address configured in the `metrics` section of the
+// config file, port 9100 by default, or nil if metrics are disabled
+func NewMetricsServer(cfg config.Config) *http.Server {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+	address := cfg.Metrics.Address
+	if address == "" {
+		address = ":9100"
+	}
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/metrics", metrics.Handler())
+	return &http.Server{
+		Addr:    address,
+		Handler: serveMux,
+	}
+}
+
 // HTTPServerWithSecureTLSOptions returns a http server configured to use
 // secure cipher suites and curves as defined by the german federal office
 // for information security (BSI) in TR-02102-2 version 2018-01
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// Validate implements the `validate` command. It checks that the tables
+// and columns referenced in descriptor.json exist in the configured
+// database and have compatible data types, and writes every problem found
+// to stdout together with the JSON path of the offending property.
+func Validate(cfg config.Config, stdout io.Writer) error {
+	schema, err := readSchema(cfg)
+	if err != nil {
+		return err
+	}
+	err = introspect.Validate(cfg.Descriptor, schema)
+	if problems, ok := err.(introspect.Problems); ok {
+		for _, problem := range problems {
+			fmt.Fprintln(stdout, problem)
+		}
+		return fmt.Errorf("descriptor.json has %d problem(s)", len(problems))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "descriptor.json matches the database schema")
+	return nil
+}
@@ -0,0 +1,201 @@
+// Package audit records the write operations performed through the
+// workflow connector, so that it can be determined who changed which
+// record and when
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+const defaultBufferSize = 1024
+
+// Counters exposes the amount of audit records that were written, dropped
+// because the buffer was full, or failed to be written to the sink
+var Counters = expvar.NewMap("audit")
+
+// Record describes a single write operation
+type Record struct {
+	Timestamp      time.Time          `json:"timestamp"`
+	Principal      string             `json:"principal,omitempty"`
+	Operation      string             `json:"operation"`
+	TypeDescriptor string             `json:"typeDescriptor,omitempty"`
+	ID             string             `json:"id,omitempty"`
+	Tx             string             `json:"tx,omitempty"`
+	Changes        map[string]*Change `json:"changes,omitempty"`
+}
+
+// Change holds the value of a field before and after a write operation
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Sink persists audit records
+type Sink interface {
+	Write(*Record) error
+	Close() error
+}
+
+// Auditor writes audit records to a sink in the background, so that
+// writing audit records never blocks the request being audited
+type Auditor struct {
+	records chan *Record
+	sink    Sink
+	done    chan struct{}
+	once    sync.Once
+}
+
+// New returns an Auditor writing to sink, which queues up to bufferSize
+// records
+func New(sink Sink, bufferSize int) *Auditor {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	a := &Auditor{
+		records: make(chan *Record, bufferSize),
+		sink:    sink,
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Log queues the record for writing. If the queue is full the record is
+// dropped and an error is logged.
+func (a *Auditor) Log(record *Record) {
+	select {
+	case a.records <- record:
+	default:
+		Counters.Add("dropped", 1)
+		log.When(true).Errorf(
+			"[audit] buffer is full, dropping record of %s %s/%s\n",
+			record.Operation, record.TypeDescriptor, record.ID,
+		)
+	}
+}
+
+// Close writes the records that are still queued and closes the sink
+func (a *Auditor) Close() error {
+	a.once.Do(func() { close(a.records) })
+	<-a.done
+	return a.sink.Close()
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+	for record := range a.records {
+		if err := a.sink.Write(record); err != nil {
+			Counters.Add("failed", 1)
+			log.When(true).Errorf(
+				"[audit] unable to write record of %s %s/%s: %s\n",
+				record.Operation, record.TypeDescriptor, record.ID, err,
+			)
+			continue
+		}
+		Counters.Add("written", 1)
+	}
+}
+
+// NewFromConfig returns an Auditor writing to the file or database table
+// specified in the `audit` section of the config file. The exec and
+// placeholder functions of the current backend are used to insert
+// records into the audit table.
+func NewFromConfig(cfg config.Audit, exec func(context.Context, string, ...interface{}) (sql.Result, error), placeholder func(int) string) (*Auditor, error) {
+	var sink Sink
+	var err error
+	switch {
+	case cfg.File != "":
+		sink, err = NewFileSink(cfg.File)
+	case cfg.Table != "":
+		sink = NewTableSink(cfg.Table, exec, placeholder)
+	default:
+		err = fmt.Errorf("either the `file` or the `table` option must be set")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return New(sink, cfg.BufferSize), nil
+}
+
+// FileSink appends audit records as JSON lines to a file
+type FileSink struct {
+	file *os.File
+}
+
+// NewFileSink opens, or creates, the file at path for appending
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit file: %s", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (f *FileSink) Write(record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *FileSink) Close() error {
+	return f.file.Close()
+}
+
+// TableSink inserts audit records into a database table with the columns
+// occurred_at, principal, operation, type_descriptor, record_id, tx and
+// changes. The changes are stored as a JSON document.
+type TableSink struct {
+	query string
+	exec  func(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// NewTableSink returns a TableSink inserting into table
+func NewTableSink(table string, exec func(context.Context, string, ...interface{}) (sql.Result, error), placeholder func(int) string) *TableSink {
+	var placeholders []string
+	for i := 1; i <= 7; i++ {
+		placeholders = append(placeholders, placeholder(i))
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (occurred_at, principal, operation, type_descriptor, record_id, tx, changes) VALUES (%s)",
+		table,
+		strings.Join(placeholders, ", "),
+	)
+	return &TableSink{query: query, exec: exec}
+}
+
+func (t *TableSink) Write(record *Record) error {
+	changes, err := json.Marshal(record.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		context.Background(),
+		t.query,
+		record.Timestamp,
+		record.Principal,
+		record.Operation,
+		record.TypeDescriptor,
+		record.ID,
+		record.Tx,
+		string(changes),
+	)
+	return err
+}
+
+func (t *TableSink) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Expected no error, instead got: '%v'", err)
+	}
+	auditor := New(sink, 10)
+	auditor.Log(&Record{
+		Timestamp:      time.Date(2019, 11, 4, 9, 30, 0, 0, time.UTC),
+		Principal:      "wfauser",
+		Operation:      "UpdateSingle",
+		TypeDescriptor: "equipment",
+		ID:             "1",
+		Changes: map[string]*Change{
+			"name": {Before: "Bialetti Moka Express", After: "Bialetti Moka Express 6 cup"},
+		},
+	})
+	auditor.Log(&Record{Operation: "CommitTx", Tx: "c0ffee"})
+	if err := auditor.Close(); err != nil {
+		t.Fatalf("Expected no error, instead got: '%v'", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []*Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			t.Fatalf("Expected every line to be a JSON record, instead got: '%v'", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got: %d", len(records))
+	}
+	change := records[0].Changes["name"]
+	if change == nil || change.Before != "Bialetti Moka Express" || change.After != "Bialetti Moka Express 6 cup" {
+		t.Errorf("Expected the before and after values of `name`, got: '%+v'", change)
+	}
+	if records[1].Tx != "c0ffee" {
+		t.Errorf("Expected: 'c0ffee', got: '%s'", records[1].Tx)
+	}
+}
+
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (b *blockingSink) Write(*Record) error {
+	<-b.release
+	return errors.New("sink unavailable")
+}
+
+func (b *blockingSink) Close() error { return nil }
+
+func TestAuditorNeverBlocks(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	auditor := New(sink, 1)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			auditor.Log(&Record{Operation: "CreateSingle"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected logging audit records to never block")
+	}
+	close(sink.release)
+	auditor.Close()
+}
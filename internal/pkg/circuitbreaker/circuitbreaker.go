@@ -0,0 +1,127 @@
+// Package circuitbreaker stops calls to the database while it is down, so
+// that requests fail fast instead of waiting for connection timeouts
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults used if no threshold or open timeout is configured
+const (
+	DefaultThreshold   = 5
+	DefaultOpenTimeout = 30 * time.Second
+)
+
+// ErrOpen is returned instead of calling the database while the circuit
+// breaker is open
+var ErrOpen = errors.New("circuit breaker is open: the database is unavailable")
+
+// State of a circuit breaker
+type State int
+
+const (
+	// Closed lets all calls through
+	Closed State = iota
+	// Open rejects all calls
+	Open
+	// HalfOpen lets a single trial call through to probe the database
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker opens after `threshold` consecutive failed calls. Once open, it
+// rejects all calls for `openTimeout` and then lets a single trial call
+// through. The breaker closes again if the trial succeeds, otherwise it
+// stays open for another `openTimeout`.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// New returns a closed circuit breaker
+func New(threshold int, openTimeout time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultOpenTimeout
+	}
+	return &Breaker{threshold: threshold, openTimeout: openTimeout, now: time.Now}
+}
+
+// Allow returns ErrOpen if the call must not be made. Otherwise the
+// outcome of the call must be reported using Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentState() {
+	case Open:
+		return ErrOpen
+	case HalfOpen:
+		if b.trial {
+			return ErrOpen
+		}
+		b.state, b.trial = HalfOpen, true
+	}
+	return nil
+}
+
+// Success reports that a call succeeded
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state, b.failures, b.trial = Closed, 0, false
+}
+
+// Failure reports that a call failed because the database is unavailable
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state, b.openedAt, b.trial = Open, b.now(), false
+	}
+}
+
+// Ignore reports that a call was made but its outcome does not tell
+// whether the database is available
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen {
+		b.trial = false
+	}
+}
+
+// State returns the current state of the circuit breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,45 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected breaker to stay closed below the threshold, got: %v", err)
+	}
+	b.Failure()
+	if got := b.State(); got != Open {
+		t.Fatalf("Expected breaker to open at the threshold, got: %s", got)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("Expected open breaker to reject calls, got: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the open timeout, got: %v", err)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("Expected only a single trial call, got: %v", err)
+	}
+	b.Failure()
+	if got := b.State(); got != Open {
+		t.Fatalf("Expected failed trial to reopen the breaker, got: %s", got)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the open timeout, got: %v", err)
+	}
+	b.Success()
+	if got := b.State(); got != Closed {
+		t.Errorf("Expected successful trial to close the breaker, got: %s", got)
+	}
+}
@@ -1,10 +1,19 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
 	"github.com/signavio/workflow-connector/internal/pkg/log"
@@ -25,6 +34,20 @@ type Config struct {
 		Driver string
 		URL    string
 		Tables []*Table
+		// The connection pool is limited to MaxOpenConns connections, of
+		// which MaxIdleConns are kept open while idle. Connections are
+		// closed after ConnMaxLifetime or after being idle for
+		// ConnMaxIdleTime. Zero values keep the defaults of database/sql.
+		MaxOpenConns    int
+		MaxIdleConns    int
+		ConnMaxLifetime time.Duration
+		ConnMaxIdleTime time.Duration
+		// StatementTimeout limits the time the database may take to
+		// answer the queries of a single request
+		StatementTimeout time.Duration
+		Retry            Retry
+		CircuitBreaker   CircuitBreaker
+		SlowQueryLog     SlowQueryLog
 	}
 	TLS struct {
 		Enabled    bool
@@ -32,8 +55,30 @@ type Config struct {
 		PrivateKey string
 	}
 	Descriptor *descriptor.Descriptor
-	Auth       *Auth
-	Logging    bool
+	// DescriptorFilePath is the descriptor.json file, a directory or a glob
+	// pattern of JSON and YAML descriptor files relative to the config
+	// directory, see descriptorFiles
+	DescriptorFilePath string
+	Auth               *Auth
+	RateLimit          RateLimit
+	// IPAllowlist restricts which networks are allowed to send requests
+	IPAllowlist IPAllowlist
+	// TrustedProxies lists the networks of reverse proxies whose
+	// `X-Forwarded-For` and `Forwarded` headers are trusted
+	TrustedProxies []string
+	Audit          Audit
+	Metrics        Metrics
+	Tracing        Tracing
+	Shutdown       Shutdown
+	ChoiceOptions  ChoiceOptions
+	Logging        bool
+	// LogLevel is the minimum level (debug, info, warn or error) of log
+	// entries, which are written in LogFormat (text or json)
+	LogLevel  string
+	LogFormat string
+	// secrets are the values resolved from secret references, which are
+	// redacted from the logs while these options are in place
+	secrets []string
 }
 
 // Table defines the name of the database table that will be queried
@@ -46,10 +91,183 @@ type Table struct {
 
 // Auth stores the username and password hash. Inbound HTTP request must
 // be authenticated over HTTP Basic Auth and the credentials provided
-// by the client will be compared to values stored here
+// by the client will be compared to values stored here. Further
+// principals can be listed in `Users`. Only the principals listed in
+// `Admins` may reload the configuration, see IsAdmin.
 type Auth struct {
 	Username     string
 	PasswordHash string
+	Attributes   map[string]string
+	Users        []*User
+	Admins       []string
+}
+
+// IsAdmin reports whether the principal with the given username may use
+// the admin routes. If no `Admins` are configured, only the principal of
+// the `auth` section itself is an admin.
+func (a *Auth) IsAdmin(username string) bool {
+	if a == nil || username == "" {
+		return false
+	}
+	if len(a.Admins) == 0 {
+		return username == a.Username
+	}
+	for _, admin := range a.Admins {
+		if username == admin {
+			return true
+		}
+	}
+	return false
+}
+
+// User is a principal that can authenticate over HTTP Basic Auth. The
+// attributes of a user, for example its cost center, can be referenced
+// in the row filters of type descriptors.
+type User struct {
+	Username     string
+	PasswordHash string
+	Attributes   map[string]string
+}
+
+// RateLimit configures the token buckets used to throttle inbound requests
+// per client IP address and per username, as well as the lockout applied
+// to clients that repeatedly fail to authenticate
+type RateLimit struct {
+	Enabled     bool
+	PerClient   TokenBucket
+	PerUsername TokenBucket
+	Lockout     Lockout
+}
+
+// TokenBucket allows `Burst` requests at once, refilled at a rate of
+// `Rate` requests per second
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+}
+
+// Lockout locks out a client IP address after `Threshold` consecutive
+// authentication failures. The lockout starts at `BaseDelay` and doubles
+// with every further failure up to a maximum of `MaxDelay`
+type Lockout struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// IPAllowlist defines the networks, in CIDR notation, that are allowed to
+// access the connector. `Networks` applies to all routes, while the
+// networks listed in `TypeDescriptors` further restrict access to the
+// routes of the type descriptor with the given key.
+type IPAllowlist struct {
+	Enabled         bool
+	Networks        []string
+	TypeDescriptors []*TypeDescriptorNetworks
+}
+
+// TypeDescriptorNetworks restricts access to the routes of a single
+// type descriptor
+type TypeDescriptorNetworks struct {
+	Key      string
+	Networks []string
+}
+
+// Audit records every successful write operation, either as JSON lines
+// appended to `File` or as rows inserted into the database table `Table`.
+// Records are written in the background; up to `BufferSize` records are
+// queued before further records are dropped.
+type Audit struct {
+	Enabled    bool
+	File       string
+	Table      string
+	BufferSize int
+}
+
+// Metrics exposes Prometheus metrics at `/metrics` on a separate HTTP
+// server listening on `Address`, so that access to the metrics can be
+// restricted independently of the API
+type Metrics struct {
+	Enabled bool
+	Address string
+}
+
+// Tracing exports OpenTelemetry spans to the OTLP/HTTP collector at
+// `Endpoint`. Only a `SampleRatio` share of the traces started by the
+// workflow connector is sampled; traces started by the caller follow the
+// sampling decision of the caller.
+type Tracing struct {
+	Enabled     bool
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64
+}
+
+// Retry retries reads and the creation of transactions that failed with a
+// transient database error, such as a refused connection or a deadlock,
+// up to `MaxAttempts` times in total. The delay between attempts starts at
+// `BaseDelay` and doubles with every attempt up to `MaxDelay`.
+type Retry struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// CircuitBreaker rejects database calls with 503 Service Unavailable once
+// `FailureThreshold` consecutive calls failed with a transient error. After
+// `OpenTimeout` a single call is let through to probe the database.
+type CircuitBreaker struct {
+	Enabled          bool
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// SlowQueryLog logs every query that takes longer than `Threshold`. A
+// share of `ExplainSampleRate`, between 0 and 1, of the slow statements is
+// explained on postgres, mysql and sqlite and the query plan is logged too.
+type SlowQueryLog struct {
+	Threshold         time.Duration
+	ExplainSampleRate float64
+}
+
+// Shutdown waits up to `DrainTimeout` for the requests in flight to be
+// handled before the workflow connector exits. Transactions that are still
+// open afterwards are rolled back.
+type Shutdown struct {
+	DrainTimeout time.Duration
+}
+
+// ChoiceOptions reloads the options of choice fields with an
+// `optionsFromTable` from their lookup tables every `RefreshInterval`.
+// The options are always loaded at startup and when the configuration
+// is reloaded, a zero interval disables the periodic refresh.
+type ChoiceOptions struct {
+	RefreshInterval time.Duration
+}
+
+// ParseNetworks parses a list of networks in CIDR notation. Single IP
+// addresses are treated as networks containing only that address.
+func ParseNetworks(networks []string) (parsed []*net.IPNet, err error) {
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			ip := net.ParseIP(network)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", network)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			parsed = append(parsed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %s", err)
+		}
+		parsed = append(parsed, ipNet)
+	}
+	return parsed, nil
 }
 
 // db is a command line flag that takes a comma seperated list of databases to test
@@ -98,26 +316,238 @@ func init() {
 	// imported as environment variables.
 	replacer := strings.NewReplacer(".", "_")
 	viper.SetEnvKeyReplacer(replacer)
+	cfg, err := load()
+	if err != nil {
+		log.When(true).Fatalf("%v\n", err)
+	}
+	if err := log.Configure(cfg.LogLevel, cfg.LogFormat); err != nil {
+		log.When(true).Fatalf("Invalid logging options in config file: %v\n", err)
+	}
+	Options = cfg
+	setSecrets(cfg.secrets)
+	logging.Store(cfg.Logging)
+}
+
+// ReloadLock is held for writing while Reload swaps the options. It is
+// held for reading only while the options are copied, see Snapshot, so
+// that a long running request does not hold back a reload and with it
+// every request that arrives after the reload.
+var ReloadLock sync.RWMutex
+
+// logging mirrors the `logging` option, so that it can be read without
+// holding ReloadLock, for example by goroutines outliving a request
+var logging atomic.Bool
+
+// Logging reports whether the `logging` option is enabled
+func Logging() bool {
+	return logging.Load()
+}
+
+type contextKey string
+
+const optionsKey = contextKey("options")
+
+// Snapshot returns a copy of ctx carrying a copy of the current options.
+// A request handled using the snapshot sees either the previous or the
+// reloaded options, but never a mix of both. The descriptor is shared
+// with the current options, and must therefore not be changed in place
+// once the workflow connector is running.
+func Snapshot(ctx context.Context) context.Context {
+	ReloadLock.RLock()
+	cfg := Options
+	ReloadLock.RUnlock()
+	return WithOptions(ctx, &cfg)
+}
+
+// WithOptions returns a copy of ctx carrying cfg, so that goroutines
+// outliving a request can keep using the request's options
+func WithOptions(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, optionsKey, cfg)
+}
+
+// Current returns the options carried by ctx. Without a snapshot, for
+// example while the workflow connector starts or in the goroutine
+// reloading the configuration, the current options are returned.
+func Current(ctx context.Context) *Config {
+	if ctx != nil {
+		if cfg, ok := ctx.Value(optionsKey).(*Config); ok {
+			return cfg
+		}
+	}
+	return &Options
+}
+
+// reloading serializes calls to Reload, which is the only writer of the
+// options once the workflow connector is running
+var reloading sync.Mutex
+
+// Reload reads the config file and the descriptor.json file again and
+// swaps them in. Before, prepare is called with the new options to build
+// the state derived from them, such as the schema mappings, while
+// requests are still handled using the current options. The returned
+// swap function is called together with swapping the options, while no
+// snapshot of the options is taken. If either file is invalid or prepare fails, the
+// current options are kept and an error is returned. The connection to
+// the database is not reopened, so changes to the database driver and
+// URL require a restart.
+func Reload(prepare func(cfg *Config) (swap func(), err error)) error {
+	reloading.Lock()
+	defer reloading.Unlock()
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+	cfg.Database.Driver = Options.Database.Driver
+	cfg.Database.URL = Options.Database.URL
+	var swap func()
+	if prepare != nil {
+		if swap, err = prepare(&cfg); err != nil {
+			return err
+		}
+	}
+	ReloadLock.Lock()
+	defer ReloadLock.Unlock()
+	if err := log.Configure(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return fmt.Errorf("Invalid logging options in config file: %v", err)
+	}
+	Options = cfg
+	setSecrets(cfg.secrets)
+	logging.Store(cfg.Logging)
+	if swap != nil {
+		swap()
+	}
+	return nil
+}
+
+// load parses the config file and the descriptor.json file located in the
+// same directory
+func load() (cfg Config, err error) {
 	if err := viper.ReadInConfig(); err != nil {
-		log.When(true).Fatalf("Can not parse config file: %v\n", err)
+		return cfg, fmt.Errorf("Can not parse config file: %v", err)
+	}
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("Unable to decode config file into struct: %s", err)
+	}
+	if cfg.secrets, err = ResolveSecrets(&cfg); err != nil {
+		return cfg, fmt.Errorf("Unable to resolve secret in config file: %v", err)
 	}
-	if err := viper.Unmarshal(&Options); err != nil {
-		log.When(true).Fatalf("Unable to decode config file into struct: %s", err)
+	if err := validateNetworks(cfg); err != nil {
+		return cfg, fmt.Errorf("Invalid network in config file: %v", err)
 	}
-	descriptorFile, err := os.Open(descriptorFilePath())
+	if cfg.Audit.Enabled && cfg.Audit.File == "" && cfg.Audit.Table == "" {
+		return cfg, fmt.Errorf("Either the `file` or the `table` option of `audit` must be set in config file")
+	}
+	files, err := descriptorFiles(cfg.DescriptorFilePath)
+	if errors.Is(err, os.ErrNotExist) && flag.Arg(0) == "introspect" {
+		// The introspect command generates the missing descriptor.json
+		cfg.Descriptor = &descriptor.Descriptor{}
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("Unable to open descriptor.json file: %v", err)
+	}
+	cfg.Descriptor, err = descriptor.ParseDescriptorFiles(files)
 	if err != nil {
-		log.When(true).Fatalf("Unable to open descriptor.json file: %v\n", err)
+		return cfg, err
+	}
+	for _, td := range cfg.Descriptor.TypeDescriptors {
+		cfg.Database.Tables = append(cfg.Database.Tables,
+			&Table{td.Table(), td.ColumnAsOptionName})
+	}
+	return cfg, nil
+}
+
+// descriptorFiles returns the descriptor files matched by path, which is
+// relative to the config directory and defaults to `descriptor.json`. If
+// path is a directory, all JSON and YAML files in it are returned, and if
+// it is a glob pattern, all JSON and YAML files matching it. The files are
+// sorted by name, so that the merged descriptor does not depend on the
+// file system.
+func descriptorFiles(path string) ([]string, error) {
+	path = descriptorPath(path)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, entry := range entries {
+			if !entry.IsDir() && descriptor.IsDescriptorFile(entry.Name()) {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("directory %s contains no JSON or YAML files: %w", path, os.ErrNotExist)
+		}
+		return files, nil
+	case err == nil:
+		return []string{path}, nil
+	case hasGlobMeta(path):
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && descriptor.IsDescriptorFile(match) {
+				files = append(files, match)
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no JSON or YAML files match %s: %w", path, os.ErrNotExist)
+		}
+		sort.Strings(files)
+		return files, nil
+	default:
+		return nil, err
+	}
+}
+
+// isDescriptorFile reports whether the file name is, or would be, one of
+// the descriptor files matched by path
+func isDescriptorFile(path, name string) bool {
+	path = descriptorPath(path)
+	name = filepath.Clean(name)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Dir(name) == path && descriptor.IsDescriptorFile(name)
 	}
-	Options.Descriptor = descriptor.ParseDescriptorFile(descriptorFile)
-	for _, td := range Options.Descriptor.TypeDescriptors {
-		Options.Database.Tables = append(Options.Database.Tables,
-			&Table{td.TableName, td.ColumnAsOptionName})
+	if hasGlobMeta(path) {
+		matched, _ := filepath.Match(path, name)
+		return matched && descriptor.IsDescriptorFile(name)
 	}
+	return name == path
 }
-func descriptorFilePath() string {
-	configFile := viper.ConfigFileUsed()
-	configDir := filepath.Dir(configFile)
-	return filepath.Join(configDir, "descriptor.json")
+
+// descriptorPath resolves path relative to the config directory
+func descriptorPath(path string) string {
+	if path == "" {
+		path = "descriptor.json"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), path)
+	}
+	return filepath.Clean(path)
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func validateNetworks(cfg Config) error {
+	if _, err := ParseNetworks(cfg.TrustedProxies); err != nil {
+		return err
+	}
+	if _, err := ParseNetworks(cfg.IPAllowlist.Networks); err != nil {
+		return err
+	}
+	for _, td := range cfg.IPAllowlist.TypeDescriptors {
+		if _, err := ParseNetworks(td.Networks); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (f db) HasChanged() bool           { return false }
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDescriptorFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "descriptors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"root.json", "equipment.yaml", "README.md", "recipes.json~"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "archive.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "equipment.yaml"), filepath.Join(dir, "root.json")}
+	for _, path := range []string{dir, filepath.Join(dir, "*")} {
+		files, err := descriptorFiles(path)
+		if err != nil {
+			t.Errorf("Expected no error for %s, instead got: '%v'", path, err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("Expected only the JSON and YAML files to match %s, got: %v", path, files)
+		}
+	}
+	if _, err := descriptorFiles(filepath.Join(dir, "*.md")); err == nil {
+		t.Errorf("Expected an error if no JSON or YAML files match")
+	}
+	if isDescriptorFile(filepath.Join(dir, "*"), filepath.Join(dir, "README.md")) {
+		t.Errorf("Expected changes to README.md not to reload the descriptor")
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	auth := &Auth{Username: "wfauser", Users: []*User{{Username: "controller"}, {Username: "operator"}}}
+	if !auth.IsAdmin("wfauser") || auth.IsAdmin("controller") || auth.IsAdmin("") {
+		t.Errorf("Expected only the user of the auth section to be an admin if no admins are configured")
+	}
+	auth.Admins = []string{"controller"}
+	if !auth.IsAdmin("controller") || auth.IsAdmin("wfauser") || auth.IsAdmin("operator") {
+		t.Errorf("Expected only the configured admins to be admins")
+	}
+	if (*Auth)(nil).IsAdmin("wfauser") {
+		t.Errorf("Expected nobody to be an admin without an auth section")
+	}
+}
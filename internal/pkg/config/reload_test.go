@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	previous := Options
+	defer func() { Options = previous }()
+	t.Run("success cases", func(t *testing.T) {
+		Options.Port = "changed"
+		var portWhilePreparing, portWhileSwapping string
+		err := Reload(func(cfg *Config) (func(), error) {
+			portWhilePreparing = Options.Port
+			return func() { portWhileSwapping = Options.Port }, nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, instead got: '%v'", err)
+		}
+		if portWhilePreparing != "changed" {
+			t.Errorf("Expected the current options to be in place while preparing, got port: '%s'", portWhilePreparing)
+		}
+		if Options.Port == "changed" || portWhileSwapping != Options.Port {
+			t.Errorf("Expected the reloaded options to be in place, got port: '%s'", Options.Port)
+		}
+	})
+	t.Run("failure cases", func(t *testing.T) {
+		Options.Port = "changed"
+		err := Reload(func(cfg *Config) (func(), error) {
+			return nil, errors.New("table does not exist")
+		})
+		if err == nil {
+			t.Errorf("Expected the error of prepare to be returned")
+		}
+		if Options.Port != "changed" {
+			t.Errorf("Expected the current options to be kept, got port: '%s'", Options.Port)
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	previous := Options
+	defer func() { Options = previous }()
+	Options.Port = "changed"
+	ctx := Snapshot(context.Background())
+	if err := Reload(nil); err != nil {
+		t.Fatalf("Expected no error, instead got: '%v'", err)
+	}
+	if Current(ctx).Port != "changed" {
+		t.Errorf("Expected the snapshot to keep the options in place when it was taken, got port: '%s'", Current(ctx).Port)
+	}
+	if Current(context.Background()) != &Options {
+		t.Errorf("Expected the current options without a snapshot")
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// secretReference matches references like `${file:/run/secrets/db_password}`
+// or `${env:DB_PASS}` in string values of the config file
+var secretReference = regexp.MustCompile(`\$\{(file|env):([^}]+)\}`)
+
+// secrets holds every value that was resolved from a secret reference
+// of the current options, so that it can be redacted before being written
+// to the logs. It is replaced together with the options, so that rotated
+// secrets are not kept forever.
+var secrets struct {
+	sync.RWMutex
+	values []string
+}
+
+// ResolveSecrets replaces every secret reference contained in a string
+// field of cfg with the contents of the referenced file or environment
+// variable. References can be embedded in a larger string, for example
+// `postgres://bob:${env:DB_PASS}@localhost/test`. The resolved secrets
+// are returned, and are redacted once they are passed to setSecrets.
+func ResolveSecrets(cfg interface{}) (resolved []string, err error) {
+	err = resolveSecrets(reflect.ValueOf(cfg), "", &resolved)
+	return resolved, err
+}
+
+func resolveSecrets(v reflect.Value, path string, resolved *[]string) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return resolveSecrets(v.Elem(), path, resolved)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				// unexported field
+				continue
+			}
+			fieldPath := strings.TrimPrefix(path+"."+v.Type().Field(i).Name, ".")
+			if err := resolveSecrets(v.Field(i), fieldPath, resolved); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveSecrets(v.Index(i), fmt.Sprintf("%s[%d]", path, i), resolved); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			if value.Kind() != reflect.String {
+				if err := resolveSecrets(value, fmt.Sprintf("%s.%v", path, key), resolved); err != nil {
+					return err
+				}
+				continue
+			}
+			s, err := resolveString(value.String(), resolved)
+			if err != nil {
+				return fmt.Errorf("%s.%v: %s", path, key, err)
+			}
+			v.SetMapIndex(key, reflect.ValueOf(s).Convert(value.Type()))
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := resolveString(v.String(), resolved)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+func resolveString(s string, secrets *[]string) (resolved string, err error) {
+	resolved = secretReference.ReplaceAllStringFunc(s, func(reference string) string {
+		if err != nil {
+			return reference
+		}
+		matches := secretReference.FindStringSubmatch(reference)
+		var secret string
+		switch matches[1] {
+		case "file":
+			var content []byte
+			content, err = ioutil.ReadFile(matches[2])
+			if err != nil {
+				err = fmt.Errorf("unable to read secret from file: %s", err)
+				return reference
+			}
+			secret = strings.TrimRight(string(content), "\r\n")
+		case "env":
+			var ok bool
+			secret, ok = os.LookupEnv(matches[2])
+			if !ok {
+				err = fmt.Errorf("environment variable %s is not set", matches[2])
+				return reference
+			}
+		}
+		if secret != "" {
+			*secrets = append(*secrets, secret)
+		}
+		return secret
+	})
+	return resolved, err
+}
+
+// setSecrets replaces the secrets that are redacted by Redact
+func setSecrets(values []string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values = values
+}
+
+// dsnCredentials matches the password of data source names that are not
+// URLs, like `root:s3cret@tcp(localhost:3306)/test` for mysql or
+// `scott/tiger@localhost/orcl` for oracle
+var dsnCredentials = regexp.MustCompile(`^([^:/@\s]+[:/])(.+)@`)
+
+// keyValuePassword matches the password of key value connection strings,
+// like `server=localhost;user id=sa;password=s3cret` for sqlserver
+var keyValuePassword = regexp.MustCompile(`(?i)\b(password|pwd)=([^;&\s]+)`)
+
+// Redact replaces every secret that was resolved while loading the
+// current config file, as well as the password of URLs and connection strings,
+// with `xxxxx`
+func Redact(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		if _, ok := u.User.Password(); ok {
+			s = u.Redacted()
+		}
+	} else {
+		s = dsnCredentials.ReplaceAllString(s, "${1}xxxxx@")
+	}
+	s = keyValuePassword.ReplaceAllString(s, "${1}=xxxxx")
+	secrets.RLock()
+	defer secrets.RUnlock()
+	for _, secret := range secrets.values {
+		s = strings.Replace(s, secret, "xxxxx", -1)
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/spf13/viper"
+)
+
+// watchDebounce is the time to wait for further changes before onChange
+// is called, since editors often write a file in several steps
+const watchDebounce = 500 * time.Millisecond
+
+// Watch calls onChange whenever the config file or one of the descriptor
+// files is changed. The directories containing the files are watched
+// rather than the files themselves, so that files replaced by a rename
+// and descriptor files added to a directory are still noticed.
+func Watch(onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range watchedDirs() {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	go func() {
+		defer watcher.Close()
+		var debounce *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isWatched(event.Name) ||
+					event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if debounce != nil {
+					debounce.Stop()
+				}
+				debounce = time.AfterFunc(watchDebounce, onChange)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.When(true).Warnf("[config] unable to watch config directory: %s\n", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// watchedDirs returns the config directory and the directory containing
+// the descriptor files
+func watchedDirs() []string {
+	configDir := filepath.Clean(filepath.Dir(viper.ConfigFileUsed()))
+	descriptorDir := descriptorPath(Options.DescriptorFilePath)
+	if info, err := os.Stat(descriptorDir); err != nil || !info.IsDir() {
+		descriptorDir = filepath.Dir(descriptorDir)
+	}
+	if descriptorDir == configDir {
+		return []string{configDir}
+	}
+	return []string{configDir, descriptorDir}
+}
+
+func isWatched(name string) bool {
+	ReloadLock.RLock()
+	descriptorFilePath := Options.DescriptorFilePath
+	ReloadLock.RUnlock()
+	return filepath.Clean(name) == filepath.Clean(viper.ConfigFileUsed()) ||
+		isDescriptorFile(descriptorFilePath, name)
+}
@@ -1,12 +1,24 @@
 package descriptor
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"regexp"
+	"strings"
 )
 
+// RowFilterPlaceholder matches placeholders like `{{principal.costCenter}}`
+// in the row filter of a type descriptor
+var RowFilterPlaceholder = regexp.MustCompile(`\{\{\s*principal\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// procedureName matches the names of stored procedures, which may be
+// qualified by a schema or package. Since the name is part of the SQL
+// statement calling the procedure, nothing else is allowed.
+var procedureName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*(\.[A-Za-z_][A-Za-z0-9_$#]*)*$`)
+
 type Descriptor struct {
 	Key             string            `json:"key,omitempty"`
 	Name            string            `json:"name,omitempty"`
@@ -27,6 +39,69 @@ type TypeDescriptor struct {
 	Fields             []*Field     `json:"fields,omitempty"`
 	OptionsAvailable   bool         `json:"optionsAvailable,omitempty"`
 	FetchOneAvailable  bool         `json:"fetchOneAvailable,omitempty"`
+	RowFilter          string       `json:"rowFilter,omitempty"`
+	// View or Query replace TableName for type descriptors backed by a
+	// database view or by an SQL query, which are read only
+	View  string `json:"view,omitempty"`
+	Query string `json:"query,omitempty"`
+	// Actions are the business operations that can be carried out on a
+	// resource of the type descriptor by calling a stored procedure
+	Actions []*Action `json:"actions,omitempty"`
+}
+
+// Table returns the name under which the rows of the type descriptor are
+// queried and its schema mapping is stored. This is the name of the table
+// or view, or the key of a type descriptor backed by a query.
+func (td *TypeDescriptor) Table() string {
+	switch {
+	case td.View != "":
+		return td.View
+	case td.Query != "":
+		return td.Key
+	}
+	return td.TableName
+}
+
+// ReadOnly reports whether the type descriptor is backed by a view or an
+// SQL query, whose rows can not be created, updated or deleted
+func (td *TypeDescriptor) ReadOnly() bool {
+	return td.View != "" || td.Query != ""
+}
+
+// Action returns the action of the type descriptor with the given name,
+// or nil if there is none
+func (td *TypeDescriptor) Action(name string) *Action {
+	for _, action := range td.Actions {
+		if action.Name == name {
+			return action
+		}
+	}
+	return nil
+}
+
+// Action maps `POST /{table}/{id}/actions/{name}` to a stored procedure,
+// which is called with the id of the resource followed by the Parameters
+// in the order they are listed. The procedure returns its result either
+// in the Output parameters, or, if ResultSet is set, as rows whose
+// columns are read by the Output parameters in the order they are listed.
+type Action struct {
+	Name      string `json:"name,omitempty"`
+	Procedure string `json:"procedure,omitempty"`
+	// IdParameter is the name of the procedure's parameter receiving the
+	// id of the resource, for the databases binding parameters by name
+	IdParameter string       `json:"idParameter,omitempty"`
+	Parameters  []*Parameter `json:"parameters,omitempty"`
+	Output      []*Parameter `json:"output,omitempty"`
+	ResultSet   bool         `json:"resultSet,omitempty"`
+}
+
+// IdParameterName returns the name of the procedure's parameter receiving
+// the id of the resource, which is `id` unless configured otherwise
+func (a *Action) IdParameterName() string {
+	if a.IdParameter == "" {
+		return "id"
+	}
+	return a.IdParameter
 }
 
 type Parameter struct {
@@ -40,16 +115,36 @@ type Field struct {
 	Type         *WorkflowType `json:"type,omitempty"`
 	FromColumn   string        `json:"fromColumn,omitempty"`
 	Relationship *Relationship `json:"relationship,omitempty"`
+	// Expression makes the field a read only computed field, whose
+	// value is selected as the `fromColumn` of the type descriptor
+	Expression Expression `json:"expression,omitempty"`
+}
+
+// Expression is the SQL expression a computed field is read from, by the
+// name of the database driver. The `default` expression is used for the
+// drivers that are not listed.
+type Expression map[string]string
+
+// For returns the expression to use with the given database driver
+func (e Expression) For(driver string) (expression string, ok bool) {
+	if expression, ok = e[driver]; ok {
+		return expression, ok
+	}
+	expression, ok = e["default"]
+	return expression, ok
 }
 
 type WorkflowType struct {
-	Name        string       `json:"name,omitempty"`
-	Kind        string       `json:"kind,omitempty"`
-	Amount      *Amount      `json:"amount,omitempty"`
-	Currency    *Currency    `json:"currency,omitempty"`
-	Options     []*Option    `json:"options,omitempty"`
-	ElementType *ElementType `json:"elementType,omitempty"`
-	MultiLine   bool         `json:"multiLine,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Kind     string    `json:"kind,omitempty"`
+	Amount   *Amount   `json:"amount,omitempty"`
+	Currency *Currency `json:"currency,omitempty"`
+	Options  []*Option `json:"options,omitempty"`
+	// OptionsFromTable loads the options of a choice from a lookup
+	// table instead, replacing the options listed in the descriptor
+	OptionsFromTable *OptionsFromTable `json:"optionsFromTable,omitempty"`
+	ElementType      *ElementType      `json:"elementType,omitempty"`
+	MultiLine        bool              `json:"multiLine,omitempty"`
 }
 type Amount struct {
 	Key        string `json:"key,omitempty"`
@@ -66,6 +161,16 @@ type Option struct {
 	Id   string `json:"id,omitempty"`
 	Name string `json:"name,omitempty"`
 }
+
+// OptionsFromTable references the lookup table containing the options
+// of a choice, with the id and name of each option in IdColumn and
+// NameColumn
+type OptionsFromTable struct {
+	TableName  string `json:"tableName,omitempty"`
+	IdColumn   string `json:"idColumn,omitempty"`
+	NameColumn string `json:"nameColumn,omitempty"`
+}
+
 type ElementType struct {
 	Name string `json:"name,omitempty"`
 }
@@ -87,22 +192,34 @@ type SchemaMapping struct {
 
 // ParseDescriptorFile will parse the descriptor.json file and make sure
 // to add an `id` field if the user has not already specified it
-func ParseDescriptorFile(file io.Reader) (descriptor *Descriptor) {
+func ParseDescriptorFile(file io.Reader) (descriptor *Descriptor, err error) {
 	var content []byte
-	content, err := ioutil.ReadAll(file)
+	content, err = ioutil.ReadAll(file)
 	if err != nil {
-		panic(fmt.Errorf("Unable to read descriptor.json file: %v", err))
+		return nil, fmt.Errorf("Unable to read descriptor.json file: %v", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("Unable to unmarshal descriptor.json: file is empty")
+	}
+	if err := ValidateSchema(content); err != nil {
+		return nil, err
 	}
 	err = json.Unmarshal(content, &descriptor)
 	if err != nil {
-		panic(fmt.Errorf("Unable to unmarshal descriptor.json: %v", err))
+		return nil, fmt.Errorf("Unable to unmarshal descriptor.json: %v", err)
+	}
+	if descriptor == nil {
+		return nil, fmt.Errorf("Unable to unmarshal descriptor.json: file is empty")
 	}
 	if err := performSanityChecks(descriptor); err != nil {
-		panic(err)
+		return nil, err
 	}
 	return
 }
 func performSanityChecks(descriptor *Descriptor) error {
+	if err := errReadOnlyTableIsAmbiguous(descriptor); err != nil {
+		return err
+	}
 	for _, td := range descriptor.TypeDescriptors {
 		if err := errUniqueIdColumnAndIdColumnDiffer(td); err != nil {
 			return err
@@ -110,6 +227,12 @@ func performSanityChecks(descriptor *Descriptor) error {
 		if err := errColumnAsOptionNameAndNameColumnDiffer(td); err != nil {
 			return err
 		}
+		if err := errRowFilterIsInvalid(td); err != nil {
+			return err
+		}
+		if err := errActionIsInvalid(td); err != nil {
+			return err
+		}
 		for _, field := range td.Fields {
 			if err := errCurrencyHasDefaultValue(field, td.Key); err != nil {
 				return err
@@ -120,6 +243,28 @@ func performSanityChecks(descriptor *Descriptor) error {
 			if err := errTypeNameIsMissing(field); err != nil {
 				return err
 			}
+			if err := errComputedFieldIsInvalid(field, td.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// errReadOnlyTableIsAmbiguous rejects type descriptors backed by a view or
+// a query that are queried under the same name as another type
+// descriptor, see TypeDescriptor.Table, since the type descriptor of the
+// rows read would then be ambiguous
+func errReadOnlyTableIsAmbiguous(descriptor *Descriptor) error {
+	msg := "Unable to parse descriptor.json: " +
+		"the rows of type descriptors `%s` and `%s` are both queried as `%s`. " +
+		"The key of a type descriptor backed by a query, or its view, must differ " +
+		"from the tables and views of the other type descriptors"
+	for i, td := range descriptor.TypeDescriptors {
+		for _, other := range descriptor.TypeDescriptors[i+1:] {
+			if (td.ReadOnly() || other.ReadOnly()) && td.Table() == other.Table() {
+				return fmt.Errorf(msg, td.Key, other.Key, td.Table())
+			}
 		}
 	}
 	return nil
@@ -147,10 +292,22 @@ func errColumnAsOptionNameAndNameColumnDiffer(td *TypeDescriptor) error {
 	return nil
 }
 
+func errRowFilterIsInvalid(td *TypeDescriptor) error {
+	msg := "Unable to parse descriptor.json: " +
+		"the `rowFilter` of type descriptor `%s` contains an invalid placeholder. " +
+		"Placeholders must be of the form {{principal.attributeName}}"
+	withoutPlaceholders := RowFilterPlaceholder.ReplaceAllString(td.RowFilter, "")
+	if strings.Contains(withoutPlaceholders, "{{") ||
+		strings.Contains(withoutPlaceholders, "}}") {
+		return fmt.Errorf(msg, td.Key)
+	}
+	return nil
+}
+
 func errTypeNameIsMissing(field *Field) error {
 	msg := "Unable to parse descriptor.json: " +
 		"%s should not have an empty type name"
-	if len(field.Type.Name) == 0 {
+	if field.Type == nil || len(field.Type.Name) == 0 {
 		return fmt.Errorf(
 			msg,
 			field.Key,
@@ -163,7 +320,7 @@ func errCurrencyHasDefaultValue(field *Field, td string) error {
 	msg := "Unable to parse descriptor.json: " +
 		"%s.%s specifies a default currency value" +
 		"*and* a fromColumn. You must specify *only* one."
-	if field.Type.Name == "money" {
+	if field.Type != nil && field.Type.Name == "money" && field.Type.Currency != nil {
 		if field.Type.Currency.Value != "" &&
 			field.Type.Currency.FromColumn != "" {
 			return fmt.Errorf(
@@ -179,10 +336,53 @@ func errCurrencyHasDefaultValue(field *Field, td string) error {
 func errFromColumnPropertyIsMissing(field *Field) error {
 	msg := "Unable to parse descriptor.json: " +
 		"field of type '%s' should contain a fromColumn property"
-	if field.Type.Name != "money" && field.Relationship == nil {
+	if field.Type != nil && field.Type.Name != "money" && field.Relationship == nil {
 		if field.FromColumn == "" {
 			return fmt.Errorf(msg, field.Type.Name)
 		}
 	}
 	return nil
 }
+
+func errComputedFieldIsInvalid(field *Field, td string) error {
+	msg := "Unable to parse descriptor.json: " +
+		"%s.%s is a computed field and can therefore not be of type money " +
+		"or represent a relationship"
+	if len(field.Expression) > 0 &&
+		(field.Relationship != nil || field.Type != nil && field.Type.Name == "money") {
+		return fmt.Errorf(msg, td, field.Key)
+	}
+	return nil
+}
+
+func errActionIsInvalid(td *TypeDescriptor) error {
+	names := make(map[string]bool)
+	for _, action := range td.Actions {
+		if names[action.Name] {
+			return fmt.Errorf(
+				"Unable to parse descriptor.json: "+
+					"type descriptor `%s` contains more than one action named `%s`",
+				td.Key, action.Name,
+			)
+		}
+		names[action.Name] = true
+		if !procedureName.MatchString(action.Procedure) {
+			return fmt.Errorf(
+				"Unable to parse descriptor.json: "+
+					"the procedure of action `%s.%s` must be the name of a stored procedure, "+
+					"optionally qualified by its schema or package",
+				td.Key, action.Name,
+			)
+		}
+		for _, parameter := range append(action.Parameters, action.Output...) {
+			if parameter.Type != nil && parameter.Type.Name == "money" {
+				return fmt.Errorf(
+					"Unable to parse descriptor.json: "+
+						"parameter `%s` of action `%s.%s` can not be of type money",
+					parameter.Key, td.Key, action.Name,
+				)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package descriptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// ParseDescriptorFiles parses a descriptor that is split across several
+// JSON or YAML files and merges them into one descriptor. Every file
+// contributes type descriptors, while the top level properties such as
+// `key`, `name` and `version` must be specified in exactly one root file.
+// Each file is validated against Schema, and type descriptors whose key is
+// defined in more than one file are rejected.
+func ParseDescriptorFiles(paths []string) (*Descriptor, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Unable to parse descriptor: no descriptor files given")
+	}
+	merged := &Descriptor{}
+	root := ""
+	definedIn := make(map[string]string)
+	for _, path := range paths {
+		part, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if part.Key != "" || part.Name != "" || part.Description != "" ||
+			part.Version != 0 || part.ProtocolVersion != 0 {
+			if root != "" {
+				return nil, fmt.Errorf(
+					"Unable to merge descriptor files: both %s and %s specify the top level "+
+						"properties of the descriptor, only one root file may specify them",
+					root, path,
+				)
+			}
+			root = path
+			merged.Key = part.Key
+			merged.Name = part.Name
+			merged.Description = part.Description
+			merged.Version = part.Version
+			merged.ProtocolVersion = part.ProtocolVersion
+		}
+		for _, td := range part.TypeDescriptors {
+			if previous, ok := definedIn[td.Key]; ok {
+				return nil, fmt.Errorf(
+					"Unable to merge descriptor files: type descriptor `%s` is defined in both %s and %s",
+					td.Key, previous, path,
+				)
+			}
+			definedIn[td.Key] = path
+			merged.TypeDescriptors = append(merged.TypeDescriptors, td)
+		}
+	}
+	if root == "" {
+		return nil, fmt.Errorf(
+			"Unable to merge descriptor files: none of the files specifies the top " +
+				"level properties of the descriptor, such as its `key`, exactly one root file must specify them",
+		)
+	}
+	if err := performSanityChecks(merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// parseFile parses a single JSON or YAML descriptor file, depending on its
+// extension
+func parseFile(path string) (*Descriptor, error) {
+	name := path
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read descriptor file: %v", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("Unable to unmarshal %s: file is empty", name)
+	}
+	var pos *positions
+	if IsYAMLFile(path) {
+		content, pos, err = decodeYAML(name, content)
+	} else {
+		pos, err = decodeJSON(name, content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchema(name, content, pos); err != nil {
+		return nil, err
+	}
+	var part *Descriptor
+	if err := json.Unmarshal(content, &part); err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal %s: %v", name, err)
+	}
+	if part == nil {
+		return nil, fmt.Errorf("Unable to unmarshal %s: file is empty", name)
+	}
+	return part, nil
+}
+
+// IsDescriptorFile reports whether path has the extension of a JSON or
+// YAML descriptor file
+func IsDescriptorFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json") || IsYAMLFile(path)
+}
+
+// IsYAMLFile reports whether path has the extension of a YAML file
+func IsYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yml" || ext == ".yaml"
+}
@@ -0,0 +1,107 @@
+package descriptor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDescriptorFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "descriptor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"root.json": `{"key": "coffee", "name": "Coffee", "version": 1}`,
+		"equipment.yaml": `
+typeDescriptors:
+  - key: equipment
+    tableName: equipment
+    uniqueIdColumn: id
+    fields:
+      - key: id
+        type:
+          name: text
+        fromColumn: id
+`,
+		"recipes.json":   `{"typeDescriptors": [{"key": "recipes", "tableName": "recipes", "uniqueIdColumn": "id"}]}`,
+		"duplicate.json": `{"typeDescriptors": [{"key": "recipes", "tableName": "recipes", "uniqueIdColumn": "id"}]}`,
+		"root.yml":       "key: tea\nversion: 2\n",
+		"usage.json":     `{"typeDescriptors": [{"key": "recipes_usage", "query": "SELECT * FROM recipes", "uniqueIdColumn": "id"}, {"key": "usage", "tableName": "recipes_usage", "uniqueIdColumn": "id"}]}`,
+		"invalid.yml": `
+typeDescriptors:
+  - key: ingredients
+    tableName: ingredients
+    uniqueIdColumn: id
+    fields:
+      - key: name
+        type: {name: text}
+        fromcolumn: name
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := func(names ...string) (result []string) {
+		for _, name := range names {
+			result = append(result, filepath.Join(dir, name))
+		}
+		return
+	}
+
+	d, err := ParseDescriptorFiles(paths("equipment.yaml", "recipes.json", "root.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if d.Key != "coffee" || d.Version != 1 || len(d.TypeDescriptors) != 2 {
+		t.Errorf("Unexpected merged descriptor: %+v", d)
+	}
+	if d.TypeDescriptors[0].Fields[0].FromColumn != "id" {
+		t.Errorf("Expected fields to be read from YAML, got: %+v", d.TypeDescriptors[0].Fields[0])
+	}
+
+	testCases := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{
+			name:     "duplicate type descriptor",
+			files:    []string{"recipes.json", "duplicate.json"},
+			expected: "type descriptor `recipes` is defined in both",
+		},
+		{
+			name:     "two root files",
+			files:    []string{"root.json", "root.yml"},
+			expected: "only one root file may specify them",
+		},
+		{
+			name:     "no root file",
+			files:    []string{"equipment.yaml", "recipes.json"},
+			expected: "none of the files specifies the top level properties",
+		},
+		{
+			name:     "query colliding with a table",
+			files:    []string{"usage.json", "root.json"},
+			expected: "type descriptors `recipes_usage` and `usage` are both queried as `recipes_usage`",
+		},
+		{
+			name:     "unknown property in YAML",
+			files:    []string{"invalid.yml"},
+			expected: `line 9, column 9: /typeDescriptors/0/fields/0/fromcolumn: unknown property "fromcolumn"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDescriptorFiles(paths(tc.files...))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package descriptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// location of a value in a file, both line and column start at 1
+type location struct {
+	line, column int
+}
+
+// positions maps the JSON pointers of the values and object keys in a
+// JSON or YAML document to their locations, so that errors can refer to
+// lines and columns
+type positions struct {
+	values map[string]location
+	keys   map[string]location
+}
+
+func newPositions() *positions {
+	return &positions{
+		values: make(map[string]location),
+		keys:   make(map[string]location),
+	}
+}
+
+// jsonPositions records the locations of all values and object keys of
+// the JSON document content
+func jsonPositions(content []byte) (*positions, error) {
+	p := newPositions()
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if err := p.walkJSON(content, dec, ""); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *positions) walkJSON(content []byte, dec *json.Decoder, pointer string) error {
+	start := skipSeparators(content, dec.InputOffset())
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	p.values[pointer] = offsetLocation(content, start)
+	switch token {
+	case json.Delim('{'):
+		for dec.More() {
+			keyStart := skipSeparators(content, dec.InputOffset())
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			child := pointer + "/" + escapePointer(key.(string))
+			p.keys[child] = offsetLocation(content, keyStart)
+			if err := p.walkJSON(content, dec, child); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := p.walkJSON(content, dec, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+// yamlPositions records the locations of all values and mapping keys of a
+// YAML document
+func yamlPositions(document *yaml.Node) *positions {
+	p := newPositions()
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 {
+		p.walkYAML(document.Content[0], "")
+	}
+	return p
+}
+
+func (p *positions) walkYAML(node *yaml.Node, pointer string) {
+	p.values[pointer] = location{node.Line, node.Column}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			child := pointer + "/" + escapePointer(key.Value)
+			p.keys[child] = location{key.Line, key.Column}
+			p.walkYAML(node.Content[i+1], child)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			p.walkYAML(item, pointer+"/"+strconv.Itoa(i))
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			p.walkYAML(node.Alias, pointer)
+			p.values[pointer] = location{node.Line, node.Column}
+		}
+	}
+}
+
+// skipSeparators returns the offset of the next token following offset,
+// skipping whitespace and the separators between tokens
+func skipSeparators(content []byte, offset int64) int64 {
+	for offset < int64(len(content)) {
+		switch content[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// offsetLocation converts an offset in content into a location
+func offsetLocation(content []byte, offset int64) location {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := content[:offset]
+	return location{
+		line:   bytes.Count(before, []byte("\n")) + 1,
+		column: int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1,
+	}
+}
+
+// describe prefixes msg with the line and column of the value at pointer,
+// or of the object key at pointer if key is true
+func (p *positions) describe(pointer string, key bool, msg string) string {
+	locations := p.values
+	if key {
+		locations = p.keys
+	}
+	path := pointer
+	if path == "" {
+		path = "/"
+	}
+	loc, ok := locations[pointer]
+	if !ok {
+		return fmt.Sprintf("%s: %s", path, msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", loc.line, loc.column, path, msg)
+}
+
+// before reports whether the value at pointer a comes before the value at
+// pointer b in the file
+func (p *positions) before(a, b string) bool {
+	la, lb := p.values[a], p.values[b]
+	return la.line < lb.line || la.line == lb.line && la.column < lb.column
+}
+
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,331 @@
+package descriptor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID identifies the JSON Schema of descriptor.json. The version at
+// the end is incremented whenever the schema changes.
+const SchemaID = "https://github.com/signavio/workflow-connector/schemas/descriptor/v6.json"
+
+// Schema is the JSON Schema that descriptor.json files are validated
+// against before they are parsed. Properties that are not listed in the
+// schema are rejected, so that typos are not silently ignored.
+const Schema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "` + SchemaID + `",
+  "title": "Workflow connector descriptor",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "key": { "type": "string", "minLength": 1 },
+    "name": { "type": "string" },
+    "description": { "type": "string" },
+    "version": { "type": "integer" },
+    "protocolVersion": { "type": "integer" },
+    "typeDescriptors": {
+      "type": "array",
+      "items": { "$ref": "#/definitions/typeDescriptor" }
+    }
+  },
+  "definitions": {
+    "typeDescriptor": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["key", "uniqueIdColumn"],
+      "oneOf": [
+        { "required": ["tableName"] },
+        { "required": ["view"] },
+        { "required": ["query"] }
+      ],
+      "properties": {
+        "key": { "type": "string", "minLength": 1 },
+        "name": { "type": "string" },
+        "tableName": { "type": "string", "minLength": 1 },
+        "view": { "type": "string", "minLength": 1 },
+        "query": { "type": "string", "minLength": 1 },
+        "columnAsOptionName": { "type": "string" },
+        "uniqueIdColumn": { "type": "string", "minLength": 1 },
+        "recordType": { "type": "string" },
+        "parameters": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/parameter" }
+        },
+        "fields": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/field" }
+        },
+        "optionsAvailable": { "type": "boolean" },
+        "fetchOneAvailable": { "type": "boolean" },
+        "rowFilter": { "type": "string" },
+        "actions": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/action" }
+        }
+      }
+    },
+    "action": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["name", "procedure"],
+      "properties": {
+        "name": { "type": "string", "pattern": "^[A-Za-z0-9_-]+$" },
+        "procedure": { "type": "string", "minLength": 1 },
+        "idParameter": { "type": "string", "minLength": 1 },
+        "parameters": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/parameter" }
+        },
+        "output": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/parameter" }
+        },
+        "resultSet": { "type": "boolean" }
+      }
+    },
+    "parameter": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["key", "type"],
+      "properties": {
+        "key": { "type": "string", "minLength": 1 },
+        "name": { "type": "string" },
+        "type": { "$ref": "#/definitions/workflowType" }
+      }
+    },
+    "field": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["key", "type"],
+      "properties": {
+        "key": { "type": "string", "minLength": 1 },
+        "name": { "type": "string" },
+        "type": { "$ref": "#/definitions/workflowType" },
+        "fromColumn": { "type": "string" },
+        "relationship": { "$ref": "#/definitions/relationship" },
+        "expression": { "$ref": "#/definitions/expression" }
+      },
+      "if": { "required": ["expression"] },
+      "then": { "required": ["fromColumn"] }
+    },
+    "expression": {
+      "type": "object",
+      "additionalProperties": false,
+      "minProperties": 1,
+      "properties": {
+        "default": { "type": "string", "minLength": 1 },
+        "sqlite3": { "type": "string", "minLength": 1 },
+        "mysql": { "type": "string", "minLength": 1 },
+        "postgres": { "type": "string", "minLength": 1 },
+        "sqlserver": { "type": "string", "minLength": 1 },
+        "godror": { "type": "string", "minLength": 1 }
+      }
+    },
+    "workflowType": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["name"],
+      "properties": {
+        "name": {
+          "enum": ["text", "number", "boolean", "date", "money", "email", "link", "choice", "user", "list"]
+        },
+        "kind": { "enum": ["date", "datetime", "time"] },
+        "amount": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "key": { "type": "string" },
+            "fromColumn": { "type": "string" }
+          }
+        },
+        "currency": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "key": { "type": "string" },
+            "fromColumn": { "type": "string" },
+            "value": { "type": "string" }
+          }
+        },
+        "options": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": false,
+            "required": ["id"],
+            "properties": {
+              "id": { "type": "string" },
+              "name": { "type": "string" }
+            }
+          }
+        },
+        "optionsFromTable": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["tableName", "idColumn", "nameColumn"],
+          "properties": {
+            "tableName": { "type": "string", "minLength": 1 },
+            "idColumn": { "type": "string", "minLength": 1 },
+            "nameColumn": { "type": "string", "minLength": 1 }
+          }
+        },
+        "elementType": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["name"],
+          "properties": {
+            "name": { "enum": ["text", "number", "boolean", "email", "link", "user"] }
+          }
+        },
+        "multiLine": { "type": "boolean" }
+      },
+      "if": { "properties": { "name": { "const": "money" } } },
+      "then": { "required": ["amount", "currency"] }
+    },
+    "relationship": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["kind", "withTable", "foreignTableUniqueIdColumn", "localTableUniqueIdColumn"],
+      "properties": {
+        "kind": { "enum": ["oneToOne", "manyToOne", "oneToMany"] },
+        "withTable": { "type": "string", "minLength": 1 },
+        "foreignTableUniqueIdColumn": { "type": "string", "minLength": 1 },
+        "localTableUniqueIdColumn": { "type": "string", "minLength": 1 }
+      }
+    }
+  }
+}
+`
+
+var (
+	compiledSchema     *jsonschema.Schema
+	compiledSchemaErr  error
+	compiledSchemaOnce sync.Once
+	// quotedName matches the names of the properties in the messages of
+	// additionalProperties errors, such as `additionalProperties 'a' not allowed`
+	quotedName = regexp.MustCompile(`'([^']*)'`)
+)
+
+// ValidateSchema validates the content of a descriptor.json file against
+// Schema. All violations are reported in a single error, each one prefixed
+// with its line, column and JSON pointer.
+func ValidateSchema(content []byte) error {
+	pos, err := decodeJSON("descriptor.json", content)
+	if err != nil {
+		return err
+	}
+	return validateSchema("descriptor.json", content, pos)
+}
+
+// decodeJSON checks the syntax of the JSON file name and returns the
+// positions of its values
+func decodeJSON(name string, content []byte) (*positions, error) {
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// The offset of a syntax error is the position after the
+			// invalid character
+			loc := offsetLocation(content, syntaxErr.Offset-1)
+			return nil, fmt.Errorf("Unable to parse %s: line %d, column %d: %v", name, loc.line, loc.column, err)
+		}
+		return nil, fmt.Errorf("Unable to parse %s: %v", name, err)
+	}
+	pos, err := jsonPositions(content)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %v", name, err)
+	}
+	return pos, nil
+}
+
+// decodeYAML converts the YAML file name into JSON and returns the
+// positions of its values in the YAML file
+func decodeYAML(name string, content []byte) ([]byte, *positions, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse %s: %v", name, err)
+	}
+	var document interface{}
+	if err := node.Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse %s: %v", name, err)
+	}
+	converted, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to convert %s to JSON: %v", name, err)
+	}
+	return converted, yamlPositions(&node), nil
+}
+
+// validateSchema validates the JSON content of the file name against
+// Schema and reports the violations at the given positions
+func validateSchema(name string, content []byte, pos *positions) error {
+	compiledSchemaOnce.Do(func() {
+		compiledSchema, compiledSchemaErr = jsonschema.CompileString(SchemaID, Schema)
+	})
+	if compiledSchemaErr != nil {
+		return fmt.Errorf("Unable to compile the JSON Schema of descriptor.json: %v", compiledSchemaErr)
+	}
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("Unable to parse %s: %v", name, err)
+	}
+	err := compiledSchema.Validate(document)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	violations := leaves(validationErr)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return pos.before(violations[i].InstanceLocation, violations[j].InstanceLocation)
+	})
+	var messages []string
+	for _, violation := range violations {
+		messages = append(messages, describeViolation(pos, violation)...)
+	}
+	return fmt.Errorf(
+		"%s does not conform to its JSON Schema:\n%s",
+		name, strings.Join(messages, "\n"),
+	)
+}
+
+// leaves returns the validation errors without causes, which are the ones
+// describing an actual violation of the schema
+func leaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var result []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		result = append(result, leaves(cause)...)
+	}
+	return result
+}
+
+// describeViolation prefixes the message of a validation error with its
+// position. Unknown properties are reported one by one at the position of
+// their key instead of the object containing them.
+func describeViolation(pos *positions, err *jsonschema.ValidationError) []string {
+	if strings.HasSuffix(err.KeywordLocation, "/additionalProperties") {
+		var messages []string
+		for _, match := range quotedName.FindAllStringSubmatch(err.Message, -1) {
+			pointer := err.InstanceLocation + "/" + escapePointer(match[1])
+			if _, ok := pos.keys[pointer]; ok {
+				messages = append(messages, pos.describe(pointer, true, "unknown property "+strconv.Quote(match[1])))
+			}
+		}
+		if len(messages) > 0 {
+			return messages
+		}
+	}
+	return []string{pos.describe(err.InstanceLocation, false, err.Message)}
+}
@@ -0,0 +1,114 @@
+package descriptor
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	content, err := ioutil.ReadFile("../../../config/descriptor.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(content); err != nil {
+		t.Fatalf("Expected example descriptor to be valid, got: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "unknown property",
+			content: `{
+  "typeDescriptors": [{
+    "key": "equipment", "tableName": "equipment", "uniqueIdColumn": "id",
+    "fields": [{"key": "name", "type": {"name": "text"}, "fromcolumn": "name"}]
+  }]
+}`,
+			expected: []string{
+				`line 4, column 58: /typeDescriptors/0/fields/0/fromcolumn: unknown property "fromcolumn"`,
+			},
+		},
+		{
+			name: "missing and invalid properties",
+			content: `{
+  "typeDescriptors": [{
+    "key": "equipment", "tableName": "equipment",
+    "fields": [{"key": "bought", "type": {"name": "date", "kind": "dateTime"}}]
+  }]
+}`,
+			expected: []string{
+				"line 2, column 23: /typeDescriptors/0: missing properties: 'uniqueIdColumn'",
+				"line 4, column 67: /typeDescriptors/0/fields/0/type/kind: value must be one of",
+			},
+		},
+		{
+			name: "unknown workflow type",
+			content: `{
+  "typeDescriptors": [{
+    "key": "equipment", "tableName": "equipment", "uniqueIdColumn": "id",
+    "fields": [{"key": "inStock", "type": {"name": "bool"}, "fromColumn": "in_stock"}]
+  }]
+}`,
+			expected: []string{
+				"line 4, column 52: /typeDescriptors/0/fields/0/type/name: value must be one of",
+			},
+		},
+		{
+			name: "computed field",
+			content: `{
+  "typeDescriptors": [{
+    "key": "equipment", "tableName": "equipment", "uniqueIdColumn": "id",
+    "fields": [{"key": "upperName", "type": {"name": "text"}, "expression": {"oracle": "UPPER(name)"}}]
+  }]
+}`,
+			expected: []string{
+				"/typeDescriptors/0/fields/0: missing properties: 'fromColumn'",
+				`/typeDescriptors/0/fields/0/expression/oracle: unknown property "oracle"`,
+			},
+		},
+		{
+			name: "table and query",
+			content: `{
+  "typeDescriptors": [{
+    "key": "usage", "tableName": "equipment", "query": "SELECT * FROM equipment", "uniqueIdColumn": "id"
+  }]
+}`,
+			expected: []string{"line 2, column 23: /typeDescriptors/0: valid against schemas at indexes 0 and 2"},
+		},
+		{
+			name: "action",
+			content: `{
+  "typeDescriptors": [{
+    "key": "equipment", "tableName": "equipment", "uniqueIdColumn": "id",
+    "actions": [{"name": "reserve stock", "parameters": [{"key": "quantity"}]}]
+  }]
+}`,
+			expected: []string{
+				"/typeDescriptors/0/actions/0: missing properties: 'procedure'",
+				"/typeDescriptors/0/actions/0/name: does not match pattern",
+				"/typeDescriptors/0/actions/0/parameters/0: missing properties: 'type'",
+			},
+		},
+		{
+			name:     "syntax error",
+			content:  "{\n  \"key\": \"coffee\",\n}",
+			expected: []string{"line 3, column 1: invalid character '}'"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSchema([]byte(tc.content))
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected error to contain %q, got: %v", expected, err)
+				}
+			}
+		})
+	}
+}
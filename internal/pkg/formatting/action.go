@@ -0,0 +1,49 @@
+package formatting
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
+)
+
+// FormatActionResults formats the results of the stored procedure of an
+// action, which are keyed by the action's output parameters. The rows of
+// a result set are returned as an array, the output parameters as an
+// object.
+func FormatActionResults(ctx context.Context, action *descriptor.Action, results []map[string]interface{}) (JSONResults []byte, err error) {
+	_, span := tracing.Start(ctx, "formatting.FormatActionResults")
+	defer func() { tracing.End(span, err) }()
+	formattedResults := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		formattedResult := make(map[string]interface{})
+		for _, output := range action.Output {
+			formattedResult[output.Key] = formatOutputValue(output.Type, result[output.Key])
+		}
+		formattedResults = append(formattedResults, formattedResult)
+	}
+	if action.ResultSet {
+		return json.MarshalIndent(&formattedResults, "", "  ")
+	}
+	formattedResult := make(map[string]interface{})
+	if len(formattedResults) > 0 {
+		formattedResult = formattedResults[0]
+	}
+	return json.MarshalIndent(&formattedResult, "", "  ")
+}
+
+// formatOutputValue formats the value of an output parameter like the
+// value of a field of the same workflow type
+func formatOutputValue(workflowType *descriptor.WorkflowType, value interface{}) interface{} {
+	dateTime, ok := value.(time.Time)
+	if !ok || workflowType == nil || workflowType.Name != "date" {
+		return formatValue(workflowType, value)
+	}
+	if workflowType.Kind == "datetime" {
+		return dateTime.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	// See buildForFieldTypeDateOrTime why dates are not converted to UTC
+	return dateTime.Format("2006-01-02T15:04:05.000Z")
+}
@@ -0,0 +1,92 @@
+package formatting
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+func TestFormatActionResults(t *testing.T) {
+	output := []*descriptor.Parameter{
+		{Key: "equipmentName", Type: &descriptor.WorkflowType{Name: "text"}},
+		{Key: "reserved", Type: &descriptor.WorkflowType{Name: "number"}},
+		{Key: "available", Type: &descriptor.WorkflowType{Name: "boolean"}},
+		{Key: "until", Type: &descriptor.WorkflowType{Name: "date", Kind: "date"}},
+		{Key: "at", Type: &descriptor.WorkflowType{Name: "date", Kind: "datetime"}},
+	}
+	cet := time.FixedZone("CET", 60*60)
+	results := []map[string]interface{}{
+		{
+			"equipmentName": "Grinder",
+			"reserved":      int64(2),
+			"available":     int64(1),
+			"until":         time.Date(2021, 3, 4, 0, 0, 0, 0, cet),
+			"at":            time.Date(2021, 3, 4, 10, 11, 12, 0, cet),
+		},
+		{"equipmentName": "Kettle"},
+	}
+	grinder := map[string]interface{}{
+		"equipmentName": "Grinder",
+		"reserved":      float64(2),
+		"available":     true,
+		"until":         "2021-03-04T00:00:00.000Z",
+		"at":            "2021-03-04T09:11:12.000Z",
+	}
+	kettle := map[string]interface{}{
+		"equipmentName": "Kettle",
+		"reserved":      nil,
+		"available":     nil,
+		"until":         nil,
+		"at":            nil,
+	}
+	testCases := []struct {
+		name    string
+		action  *descriptor.Action
+		results []map[string]interface{}
+		want    interface{}
+	}{
+		{
+			name:    "output parameters",
+			action:  &descriptor.Action{Name: "reserve", Output: output},
+			results: results[:1],
+			want:    grinder,
+		},
+		{
+			name:    "no output parameters returned",
+			action:  &descriptor.Action{Name: "reserve", Output: output},
+			results: nil,
+			want:    map[string]interface{}{},
+		},
+		{
+			name:    "result set",
+			action:  &descriptor.Action{Name: "reservations", Output: output, ResultSet: true},
+			results: results,
+			want:    []interface{}{grinder, kettle},
+		},
+		{
+			name:    "empty result set",
+			action:  &descriptor.Action{Name: "reservations", Output: output, ResultSet: true},
+			results: nil,
+			want:    []interface{}{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, err := FormatActionResults(context.Background(), tc.action, tc.results)
+			if err != nil {
+				t.Fatalf("Expected no error, instead got: '%v'", err)
+			}
+			var got interface{}
+			if err := json.Unmarshal(formatted, &got); err != nil {
+				t.Fatalf("Expected valid JSON, instead got: '%v'", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected: '%v', got: '%s'", tc.want, formatted)
+			}
+		})
+	}
+}
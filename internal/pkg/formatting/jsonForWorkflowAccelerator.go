@@ -9,6 +9,7 @@ import (
 	"github.com/signavio/workflow-connector/internal/pkg/config"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
 	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
 )
 
@@ -32,29 +33,31 @@ var (
 // Format will format the results received from the backend service,
 // accorind to the Workflow Accelerator Connector API
 func (f *standardFormatter) Format(ctx context.Context, results []interface{}) (JSONResults []byte, err error) {
+	_, span := tracing.Start(ctx, "formatting.Standard.Format")
+	defer func() { tracing.End(span, err) }()
 	if len(results) == 0 {
 		return []byte("{}"), nil
 	}
 	tableName := ctx.Value(util.ContextKey("table")).(string)
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		tableName,
 	)
 	fields := typeDescriptor.Fields
 	if len(results) == 1 {
-		log.When(config.Options.Logging).Infoln("[formatter -> asWorkflowType] Format with result set == 1")
+		log.When(config.Logging()).WithContext(ctx).Debugln("[formatter -> asWorkflowType] Format with result set == 1")
 		formattedResult := formatAsAWorkflowType(
 			ctx, results[0].(map[string]interface{}), tableName, fields,
 		)
-		log.When(config.Options.Logging).Infof("[formatter <- asWorkflowType] formattedResult: \n%+v\n", formattedResult)
+		log.When(config.Logging()).WithContext(ctx).Debugf("[formatter <- asWorkflowType] formattedResult: \n%+v\n", formattedResult)
 		JSONResults, err = json.MarshalIndent(&formattedResult, "", "  ")
 		if err != nil {
 			return nil, err
 		}
-		log.When(config.Options.Logging).Infoln("[routeHandler <- formatter]")
+		log.When(config.Logging()).WithContext(ctx).Debugln("[routeHandler <- formatter]")
 		return
 	}
-	log.When(config.Options.Logging).Infoln("[formatter -> asWorkflowType] Format with result set > 1")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[formatter -> asWorkflowType] Format with result set > 1")
 	var formattedResults []interface{}
 	for _, result := range results {
 		formattedResult := formatAsAWorkflowType(
@@ -62,7 +65,7 @@ func (f *standardFormatter) Format(ctx context.Context, results []interface{}) (
 		)
 		formattedResults = append(formattedResults, formattedResult)
 	}
-	log.When(config.Options.Logging).Infof(
+	log.When(config.Logging()).WithContext(ctx).Debugf(
 		"[formatter <- asWorkflowType] formattedResult (top 2): \n%+v ...\n",
 		formattedResults[0:1],
 	)
@@ -70,21 +73,23 @@ func (f *standardFormatter) Format(ctx context.Context, results []interface{}) (
 	if err != nil {
 		return nil, err
 	}
-	log.When(config.Options.Logging).Infoln("[routeHandler <- formatter]")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[routeHandler <- formatter]")
 	return
 }
 
 func (f *collectionFormatter) Format(ctx context.Context, results []interface{}) (JSONResults []byte, err error) {
+	_, span := tracing.Start(ctx, "formatting.Collection.Format")
+	defer func() { tracing.End(span, err) }()
 	if len(results) == 0 {
 		return []byte("[]"), nil
 	}
 	tableName := ctx.Value(util.ContextKey("table")).(string)
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		tableName,
 	)
 	fields := typeDescriptor.Fields
-	log.When(config.Options.Logging).Infoln("[formatter -> asWorkflowType] Format with result set >= 1")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[formatter -> asWorkflowType] Format with result set >= 1")
 	var formattedResults []interface{}
 	for _, result := range results {
 		formattedResult := formatAsAWorkflowType(
@@ -92,7 +97,7 @@ func (f *collectionFormatter) Format(ctx context.Context, results []interface{})
 		)
 		formattedResults = append(formattedResults, formattedResult)
 	}
-	log.When(config.Options.Logging).Infof(
+	log.When(config.Logging()).WithContext(ctx).Debugf(
 		"[formatter <- asWorkflowType] formattedResult (top 2): \n%+v ...\n",
 		formattedResults[0:1],
 	)
@@ -100,11 +105,13 @@ func (f *collectionFormatter) Format(ctx context.Context, results []interface{})
 	if err != nil {
 		return nil, err
 	}
-	log.When(config.Options.Logging).Infoln("[routeHandler <- formatter]")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[routeHandler <- formatter]")
 	return
 }
 
 func (f *getSingleAsOptionFormatter) Format(ctx context.Context, results []interface{}) (JSONResults []byte, err error) {
+	_, span := tracing.Start(ctx, "formatting.GetSingleAsOption.Format")
+	defer func() { tracing.End(span, err) }()
 	tableName := ctx.Value(util.ContextKey("table")).(string)
 	if len(results) == 0 {
 		return []byte("{}"), nil
@@ -113,16 +120,18 @@ func (f *getSingleAsOptionFormatter) Format(ctx context.Context, results []inter
 		return nil, fmt.Errorf("formatting: expected result set to contain only one resource")
 	}
 	formattedResult := stringify(results[0].(map[string]interface{})[tableName])
-	log.When(config.Options.Logging).Infof("[formatter <- asWorkflowType] formattedResult: \n%+v\n", formattedResult)
+	log.When(config.Logging()).WithContext(ctx).Debugf("[formatter <- asWorkflowType] formattedResult: \n%+v\n", formattedResult)
 	JSONResults, err = json.MarshalIndent(&formattedResult, "", "  ")
 	if err != nil {
 		return nil, err
 	}
-	log.When(config.Options.Logging).Infoln("[routeHandler <- formatter]")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[routeHandler <- formatter]")
 	return
 }
 
 func (f *getCollectionAsOptionsFormatter) Format(ctx context.Context, results []interface{}) (JSONResults []byte, err error) {
+	_, span := tracing.Start(ctx, "formatting.GetCollectionAsOptions.Format")
+	defer func() { tracing.End(span, err) }()
 	tableName := ctx.Value(util.ContextKey("table")).(string)
 	var formattedResults []interface{}
 	if len(results) == 0 {
@@ -135,7 +144,7 @@ func (f *getCollectionAsOptionsFormatter) Format(ctx context.Context, results []
 		)
 	}
 	formattedResultsSubset := subsetForPerformance(formattedResults)
-	log.When(config.Options.Logging).Infof(
+	log.When(config.Logging()).WithContext(ctx).Debugf(
 		"[formatter <- asWorkflowType] formattedResult(s): \n%+v ...\n",
 		formattedResults,
 	)
@@ -143,7 +152,7 @@ func (f *getCollectionAsOptionsFormatter) Format(ctx context.Context, results []
 	if err != nil {
 		return nil, err
 	}
-	log.When(config.Options.Logging).Infoln("[routeHandler <- formatter]")
+	log.When(config.Logging()).WithContext(ctx).Debugln("[routeHandler <- formatter]")
 	return
 }
 
@@ -178,7 +187,7 @@ func buildAndRecursivelyResolveRelationships(ctx context.Context, formatted, que
 
 func buildResultFromQueryResultsWithoutRelationships(ctx context.Context, formatted, queryResults map[string]interface{}, table string, field *descriptor.Field) map[string]interface{} {
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		table,
 	)
 	switch {
@@ -211,7 +220,7 @@ func buildResultFromQueryResultsWithoutRelationships(ctx context.Context, format
 
 func resolveOneToManyRelationship(ctx context.Context, formatted, queryResults map[string]interface{}, table string, field *descriptor.Field) map[string]interface{} {
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		field.Relationship.WithTable,
 	)
 	relatedResults := queryResults[table].(map[string]interface{})[field.Key].(map[string]interface{})[field.Relationship.WithTable].([]map[string]interface{})
@@ -229,7 +238,7 @@ func resolveOneToManyRelationship(ctx context.Context, formatted, queryResults m
 
 func resolveOneToOneOrManyToOneRelationship(ctx context.Context, formatted, queryResults map[string]interface{}, table string, field *descriptor.Field) map[string]interface{} {
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		field.Relationship.WithTable,
 	)
 	relatedResults := queryResults[table].(map[string]interface{})[field.Key].(map[string]interface{})[field.Relationship.WithTable].([]map[string]interface{})
@@ -262,7 +271,7 @@ func resolveOneToOneOrManyToOneRelationship(ctx context.Context, formatted, quer
 func denormalizeResultSet(ctx context.Context, relatedResults []map[string]interface{}, field *descriptor.Field, uniqueIdColumn string) (results []map[string]interface{}) {
 	for _, r := range relatedResults {
 		// remove relationships keys from recursively resolved subset
-		fields := withRelationshipFieldsOmitted(field.Relationship.WithTable)
+		fields := withRelationshipFieldsOmitted(ctx, field.Relationship.WithTable)
 		resolvedRelationship := formatAsAWorkflowType(
 			ctx,
 			map[string]interface{}{field.Relationship.WithTable: r}, field.Relationship.WithTable, fields,
@@ -275,7 +284,7 @@ func denormalizeResultSet(ctx context.Context, relatedResults []map[string]inter
 func normalizeResultSet(ctx context.Context, relatedResults []map[string]interface{}, field *descriptor.Field, uniqueIdColumn string) (results []interface{}) {
 	for _, r := range relatedResults {
 		// remove relationships keys from recursively resolved subset
-		fields := withRelationshipFieldsOmitted(field.Relationship.WithTable)
+		fields := withRelationshipFieldsOmitted(ctx, field.Relationship.WithTable)
 		resolvedRelationship := formatAsAWorkflowType(
 			ctx,
 			map[string]interface{}{field.Relationship.WithTable: r},
@@ -335,8 +344,10 @@ func buildForFieldTypeDateTime(formatted, queryResults map[string]interface{}, t
 
 func buildForFieldTypeOther(formatted, queryResults map[string]interface{}, table string, field *descriptor.Field) map[string]interface{} {
 	if queryResults[table].(map[string]interface{})[field.FromColumn] != nil {
-		formatted[field.Key] =
-			queryResults[table].(map[string]interface{})[field.FromColumn]
+		formatted[field.Key] = formatValue(
+			field.Type,
+			queryResults[table].(map[string]interface{})[field.FromColumn],
+		)
 		return formatted
 	}
 	formatted[field.Key] = nil
@@ -391,6 +402,8 @@ func stringify(value interface{}) (stringified interface{}) {
 		return nil
 	}
 	switch v := value.(type) {
+	case bool:
+		stringified = fmt.Sprintf("%t", v)
 	case int64:
 		stringified = fmt.Sprintf("%d", v)
 	case float64:
@@ -413,9 +426,9 @@ func tableHasRelationships(queryResults map[string]interface{}, table string, fi
 	return field.Relationship != nil && queryResults[table].(map[string]interface{})[field.Key] != nil
 }
 
-func typeDescriptorContainsRelationships(table string) bool {
+func typeDescriptorContainsRelationships(ctx context.Context, table string) bool {
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		table,
 	)
 	return len(util.TypeDescriptorRelationships(typeDescriptor)) > 0
@@ -434,9 +447,9 @@ func subsetForPerformance(in []interface{}) []interface{} {
 	return in
 }
 
-func withRelationshipFieldsOmitted(table string) (fields []*descriptor.Field) {
+func withRelationshipFieldsOmitted(ctx context.Context, table string) (fields []*descriptor.Field) {
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		table,
 	)
 	for _, field := range typeDescriptor.Fields {
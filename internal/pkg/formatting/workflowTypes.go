@@ -0,0 +1,85 @@
+package formatting
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+// formatValue formats a value read from the database according to the
+// workflow type of its field. Values that can not be converted to the
+// workflow type are returned unchanged.
+func formatValue(workflowType *descriptor.WorkflowType, value interface{}) interface{} {
+	if value == nil || workflowType == nil {
+		return value
+	}
+	switch workflowType.Name {
+	case "boolean":
+		return formatBoolean(value)
+	case "number":
+		return formatNumber(value)
+	case "text", "email", "link", "user", "choice":
+		// Choices are represented by the id of the selected option
+		return stringify(value)
+	case "list":
+		return formatList(workflowType.ElementType, value)
+	default:
+		return value
+	}
+}
+
+// formatBoolean accepts booleans as well as the small integers,
+// bits and strings that booleans are commonly stored as
+func formatBoolean(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// formatNumber accepts numbers as well as decimals that
+// the database driver returns as strings
+func formatNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64, float64:
+		return v
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// formatList parses a list that was stored in the database as a
+// JSON array, and formats each element according to the element type
+func formatList(elementType *descriptor.ElementType, value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	var elements []interface{}
+	if err := json.Unmarshal([]byte(s), &elements); err != nil {
+		return value
+	}
+	element := &descriptor.WorkflowType{Name: "text"}
+	if elementType != nil {
+		element = &descriptor.WorkflowType{Name: elementType.Name}
+	}
+	formatted := make([]interface{}, 0, len(elements))
+	for _, e := range elements {
+		formatted = append(formatted, formatValue(element, e))
+	}
+	return formatted
+}
@@ -0,0 +1,106 @@
+package formatting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
+)
+
+func TestWorkflowTypesRoundTrip(t *testing.T) {
+	colors := &descriptor.WorkflowType{
+		Name: "choice",
+		Options: []*descriptor.Option{
+			{Id: "red", Name: "Red"},
+			{Id: "green", Name: "Green"},
+		},
+	}
+	listOf := func(name string) *descriptor.WorkflowType {
+		return &descriptor.WorkflowType{
+			Name:        "list",
+			ElementType: &descriptor.ElementType{Name: name},
+		}
+	}
+	testCases := []struct {
+		name         string
+		workflowType *descriptor.WorkflowType
+		// the value as received in the request data
+		request interface{}
+		// the value as returned by the database driver, when
+		// the driver does not return the coerced value as is
+		stored interface{}
+		want   interface{}
+	}{
+		{"boolean", &descriptor.WorkflowType{Name: "boolean"}, true, nil, true},
+		{"boolean from form", &descriptor.WorkflowType{Name: "boolean"}, "false", nil, false},
+		{"boolean stored as integer", &descriptor.WorkflowType{Name: "boolean"}, true, int64(1), true},
+		{"number", &descriptor.WorkflowType{Name: "number"}, 42.5, nil, 42.5},
+		{"number from form", &descriptor.WorkflowType{Name: "number"}, "17", nil, float64(17)},
+		{"number stored as integer", &descriptor.WorkflowType{Name: "number"}, 3.0, int64(3), int64(3)},
+		{"number stored as decimal string", &descriptor.WorkflowType{Name: "number"}, 9.99, "9.99", 9.99},
+		{"text", &descriptor.WorkflowType{Name: "text"}, "hello", nil, "hello"},
+		{"email", &descriptor.WorkflowType{Name: "email"}, "jane@example.com", nil, "jane@example.com"},
+		{"link", &descriptor.WorkflowType{Name: "link"}, "https://example.com/a?b=c", nil, "https://example.com/a?b=c"},
+		{"user", &descriptor.WorkflowType{Name: "user"}, "5b4f6a1e", nil, "5b4f6a1e"},
+		{"user stored as integer", &descriptor.WorkflowType{Name: "user"}, "4711", int64(4711), "4711"},
+		{"choice", colors, "green", nil, "green"},
+		{"list of numbers", listOf("number"), []interface{}{1.0, 2.5}, nil, []interface{}{1.0, 2.5}},
+		{"list of text from form", listOf("text"), `["a","b"]`, nil, []interface{}{"a", "b"}},
+		{"list of booleans", listOf("boolean"), []interface{}{true, "false"}, nil, []interface{}{true, false}},
+		{"list of emails", listOf("email"), []interface{}{"jane@example.com"}, nil, []interface{}{"jane@example.com"}},
+		{"empty list", listOf("text"), []interface{}{}, nil, []interface{}{}},
+		{"null", &descriptor.WorkflowType{Name: "number"}, nil, nil, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			coerced, err := query.CoerceValue(tc.workflowType, tc.request)
+			if err != nil {
+				t.Fatalf("Expected no error, instead got: '%v'", err)
+			}
+			stored := coerced
+			if tc.stored != nil {
+				stored = tc.stored
+			}
+			got := formatValue(tc.workflowType, stored)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected: '%#v', got: '%#v'", tc.want, got)
+			}
+		})
+	}
+	t.Run("lists are stored as JSON arrays", func(t *testing.T) {
+		coerced, err := query.CoerceValue(listOf("number"), []interface{}{"1", 2.5})
+		if err != nil {
+			t.Fatalf("Expected no error, instead got: '%v'", err)
+		}
+		if coerced != "[1,2.5]" {
+			t.Errorf("Expected: '[1,2.5]', got: '%v'", coerced)
+		}
+	})
+	t.Run("failure cases", func(t *testing.T) {
+		failureCases := []struct {
+			workflowType *descriptor.WorkflowType
+			request      interface{}
+			want         string
+		}{
+			{&descriptor.WorkflowType{Name: "boolean"}, "maybe", `expected a boolean, got "maybe"`},
+			{&descriptor.WorkflowType{Name: "number"}, "twelve", `expected a number, got "twelve"`},
+			{&descriptor.WorkflowType{Name: "number"}, true, `expected a number, got true`},
+			{&descriptor.WorkflowType{Name: "email"}, "Jane <jane@example.com>", `expected an email address, got "Jane <jane@example.com>"`},
+			{&descriptor.WorkflowType{Name: "link"}, "/relative/path", `expected an absolute URL, got "/relative/path"`},
+			{colors, "purple", `expected one of the options "red", "green", got "purple"`},
+			{listOf("number"), []interface{}{1.0, "x"}, `element 1: expected a number, got "x"`},
+			{listOf("text"), "a,b", `expected a list, got "a,b"`},
+		}
+		for _, fc := range failureCases {
+			_, err := query.CoerceValue(fc.workflowType, fc.request)
+			if err == nil {
+				t.Errorf("Expected error '%s', instead got none", fc.want)
+				continue
+			}
+			if err.Error() != fc.want {
+				t.Errorf("Expected: '%s', got: '%s'", fc.want, err)
+			}
+		}
+	})
+}
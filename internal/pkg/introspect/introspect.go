@@ -0,0 +1,469 @@
+// Package introspect reads the schema of a live database and derives the
+// type descriptors of a descriptor.json file from it
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+// Table is the schema of a database table
+type Table struct {
+	Name        string
+	Columns     []*Column
+	PrimaryKey  []string
+	ForeignKeys []*ForeignKey
+}
+
+// Column of a database table and its data type as reported by the database
+type Column struct {
+	Name     string
+	DataType string
+}
+
+// ForeignKey references the column `ReferencedColumn` of the table
+// `ReferencedTable` from the column `Column`. Foreign keys spanning
+// multiple columns consist of one ForeignKey per column sharing the same
+// `Name`.
+type ForeignKey struct {
+	Name             string
+	Column           string
+	ReferencedTable  string
+	ReferencedColumn string
+}
+
+// Func reads the schema of every table of the current database schema
+type Func func(context.Context, *sql.DB) ([]*Table, error)
+
+// Queries retrieve the schema of the tables from the catalog of a database
+type Queries struct {
+	// Columns returns the table name, column name and data type of every
+	// column, ordered by table and by the position of the column
+	Columns string
+	// PrimaryKeys returns the table name and column name of every column
+	// of a primary key, ordered by table and by position in the key
+	PrimaryKeys string
+	// ForeignKeys returns the constraint name, table name, column name,
+	// referenced table name and referenced column name of every column
+	// of a foreign key
+	ForeignKeys string
+}
+
+// Query reads the schema of the tables using the catalog queries of a
+// database
+func Query(ctx context.Context, db *sql.DB, queries Queries) ([]*Table, error) {
+	var tables []*Table
+	byName := make(map[string]*Table)
+	err := scan(ctx, db, queries.Columns, func(values []string) {
+		table, ok := byName[values[0]]
+		if !ok {
+			table = &Table{Name: values[0]}
+			byName[values[0]] = table
+			tables = append(tables, table)
+		}
+		table.Columns = append(table.Columns, &Column{Name: values[1], DataType: values[2]})
+	}, 3)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read columns: %v", err)
+	}
+	err = scan(ctx, db, queries.PrimaryKeys, func(values []string) {
+		if table, ok := byName[values[0]]; ok {
+			table.PrimaryKey = append(table.PrimaryKey, values[1])
+		}
+	}, 2)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read primary keys: %v", err)
+	}
+	err = scan(ctx, db, queries.ForeignKeys, func(values []string) {
+		if table, ok := byName[values[1]]; ok {
+			table.ForeignKeys = append(table.ForeignKeys, &ForeignKey{
+				Name:             values[0],
+				Column:           values[2],
+				ReferencedTable:  values[3],
+				ReferencedColumn: values[4],
+			})
+		}
+	}, 5)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read foreign keys: %v", err)
+	}
+	return tables, nil
+}
+
+func scan(ctx context.Context, db *sql.DB, query string, fn func([]string), columns int) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values := make([]sql.NullString, columns)
+		dest := make([]interface{}, columns)
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		strings := make([]string, columns)
+		for i, value := range values {
+			strings[i] = value.String
+		}
+		fn(strings)
+	}
+	return rows.Err()
+}
+
+// Filter returns the tables whose name is listed in names, or all tables
+// if names is empty. An error is returned if a listed table does not exist.
+func Filter(tables []*Table, names []string) ([]*Table, error) {
+	if len(names) == 0 {
+		return tables, nil
+	}
+	var filtered []*Table
+	for _, name := range names {
+		table := find(tables, name)
+		if table == nil {
+			return nil, fmt.Errorf("table %s does not exist", name)
+		}
+		filtered = append(filtered, table)
+	}
+	return filtered, nil
+}
+
+func find(tables []*Table, name string) *Table {
+	for _, table := range tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// Merge adds a type descriptor for every table to d. Type descriptors and
+// fields that already exist in d are kept as they are, so that changes
+// made by hand are not lost; only fields for columns and relationships
+// that are not part of a type descriptor yet are appended to it.
+func Merge(d *descriptor.Descriptor, tables []*Table) *descriptor.Descriptor {
+	for _, table := range tables {
+		td := typeDescriptor(d, table.Name)
+		if td == nil {
+			td = newTypeDescriptor(d, table)
+			d.TypeDescriptors = append(d.TypeDescriptors, td)
+		}
+		for _, column := range table.Columns {
+			if hasColumn(td, column.Name) {
+				continue
+			}
+			td.Fields = append(td.Fields, &descriptor.Field{
+				Key:        fieldKey(td, column.Name),
+				Name:       displayName(column.Name),
+				FromColumn: column.Name,
+				Type:       fieldType(column.DataType),
+			})
+		}
+		for _, fk := range singleColumnForeignKeys(table) {
+			referenced := find(tables, fk.ReferencedTable)
+			if referenced == nil {
+				continue
+			}
+			referencedColumn := fk.ReferencedColumn
+			if referencedColumn == "" && len(referenced.PrimaryKey) == 1 {
+				referencedColumn = referenced.PrimaryKey[0]
+			}
+			manyToOne := &descriptor.Relationship{
+				Kind:                       "manyToOne",
+				WithTable:                  referenced.Name,
+				LocalTableUniqueIdColumn:   fk.Column,
+				ForeignTableUniqueIdColumn: referencedColumn,
+			}
+			if !hasRelationship(td, manyToOne) {
+				name := strings.TrimSuffix(strings.TrimSuffix(fk.Column, "_id"), "Id")
+				if name == fk.Column || name == "" {
+					name = referenced.Name
+				}
+				td.Fields = append(td.Fields, &descriptor.Field{
+					Key:          fieldKey(td, name),
+					Name:         displayName(name),
+					Type:         &descriptor.WorkflowType{Name: "text"},
+					Relationship: manyToOne,
+				})
+			}
+			// The referenced table lists the rows referencing it
+			referencedTd := typeDescriptor(d, referenced.Name)
+			if referencedTd == nil {
+				referencedTd = newTypeDescriptor(d, referenced)
+				d.TypeDescriptors = append(d.TypeDescriptors, referencedTd)
+			}
+			oneToMany := &descriptor.Relationship{
+				Kind:                       "oneToMany",
+				WithTable:                  table.Name,
+				LocalTableUniqueIdColumn:   referencedColumn,
+				ForeignTableUniqueIdColumn: fk.Column,
+			}
+			if !hasRelationship(referencedTd, oneToMany) {
+				referencedTd.Fields = append(referencedTd.Fields, &descriptor.Field{
+					Key:  fieldKey(referencedTd, table.Name),
+					Name: displayName(table.Name),
+					Type: &descriptor.WorkflowType{
+						Name:        "list",
+						ElementType: &descriptor.ElementType{Name: "text"},
+					},
+					Relationship: oneToMany,
+				})
+			}
+		}
+	}
+	return d
+}
+
+// newTypeDescriptor returns a type descriptor for table without fields.
+// The primary key is used as unique ID and a column called `name`, or else
+// the first text column, is used as the name of options.
+func newTypeDescriptor(d *descriptor.Descriptor, table *Table) *descriptor.TypeDescriptor {
+	td := &descriptor.TypeDescriptor{
+		Key:               typeDescriptorKey(d, table.Name),
+		Name:              displayName(table.Name),
+		TableName:         table.Name,
+		RecordType:        "value",
+		OptionsAvailable:  true,
+		FetchOneAvailable: true,
+	}
+	switch {
+	case hasTableColumn(table, "id"):
+		// The descriptor requires the unique ID column of type
+		// descriptors with an `id` field to be `id`
+		td.UniqueIdColumn = "id"
+	case len(table.PrimaryKey) == 1:
+		td.UniqueIdColumn = table.PrimaryKey[0]
+	case len(table.Columns) > 0:
+		td.UniqueIdColumn = table.Columns[0].Name
+	}
+	td.ColumnAsOptionName = td.UniqueIdColumn
+	if hasTableColumn(table, "name") {
+		td.ColumnAsOptionName = "name"
+	} else {
+		for _, column := range table.Columns {
+			if column.Name != td.UniqueIdColumn && fieldType(column.DataType).Name == "text" {
+				td.ColumnAsOptionName = column.Name
+				break
+			}
+		}
+	}
+	return td
+}
+
+// fieldType infers the workflow type of a column from its data type
+func fieldType(dataType string) *descriptor.WorkflowType {
+	switch category(dataType) {
+	case "boolean":
+		return &descriptor.WorkflowType{Name: "boolean"}
+	case "date":
+		dataType = strings.ToLower(dataType)
+		switch {
+		case strings.Contains(dataType, "timestamp"), strings.Contains(dataType, "datetime"):
+			return &descriptor.WorkflowType{Name: "date", Kind: "datetime"}
+		case strings.HasPrefix(dataType, "date"):
+			return &descriptor.WorkflowType{Name: "date", Kind: "date"}
+		default:
+			return &descriptor.WorkflowType{Name: "date", Kind: "time"}
+		}
+	case "number":
+		return &descriptor.WorkflowType{Name: "number"}
+	default:
+		return &descriptor.WorkflowType{Name: "text"}
+	}
+}
+
+// category classifies the data type of a column as `boolean`, `date`,
+// `text` or `number`. An empty string is returned for unknown data types.
+func category(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case strings.Contains(dataType, "bool"), dataType == "bit":
+		return "boolean"
+	case strings.Contains(dataType, "timestamp"), strings.Contains(dataType, "date"),
+		strings.HasPrefix(dataType, "time"):
+		return "date"
+	case strings.Contains(dataType, "char"), strings.Contains(dataType, "text"),
+		strings.Contains(dataType, "clob"):
+		return "text"
+	case strings.Contains(dataType, "int"), strings.Contains(dataType, "serial"),
+		strings.Contains(dataType, "dec"), strings.Contains(dataType, "numeric"),
+		strings.Contains(dataType, "number"), strings.Contains(dataType, "real"),
+		strings.Contains(dataType, "float"), strings.Contains(dataType, "double"),
+		strings.Contains(dataType, "money"):
+		return "number"
+	default:
+		return ""
+	}
+}
+
+// singleColumnForeignKeys returns the foreign keys of table that consist
+// of a single column, sorted by column. Foreign keys spanning multiple
+// columns can not be expressed as relationships in a type descriptor.
+func singleColumnForeignKeys(table *Table) []*ForeignKey {
+	columns := make(map[string]int)
+	for _, fk := range table.ForeignKeys {
+		columns[fk.Name]++
+	}
+	var fks []*ForeignKey
+	for _, fk := range table.ForeignKeys {
+		if columns[fk.Name] == 1 {
+			fks = append(fks, fk)
+		}
+	}
+	sort.SliceStable(fks, func(i, j int) bool { return fks[i].Column < fks[j].Column })
+	return fks
+}
+
+func typeDescriptor(d *descriptor.Descriptor, table string) *descriptor.TypeDescriptor {
+	for _, td := range d.TypeDescriptors {
+		if td.TableName == table {
+			return td
+		}
+	}
+	return nil
+}
+
+func hasTableColumn(table *Table, name string) bool {
+	for _, column := range table.Columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasColumn reports whether a field of td is read from column
+func hasColumn(td *descriptor.TypeDescriptor, column string) bool {
+	for _, field := range td.Fields {
+		if field.FromColumn == column {
+			return true
+		}
+		if field.Type != nil && field.Type.Amount != nil && field.Type.Amount.FromColumn == column {
+			return true
+		}
+		if field.Type != nil && field.Type.Currency != nil && field.Type.Currency.FromColumn == column {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRelationship(td *descriptor.TypeDescriptor, relationship *descriptor.Relationship) bool {
+	for _, field := range td.Fields {
+		if r := field.Relationship; r != nil &&
+			r.WithTable == relationship.WithTable &&
+			r.LocalTableUniqueIdColumn == relationship.LocalTableUniqueIdColumn &&
+			r.ForeignTableUniqueIdColumn == relationship.ForeignTableUniqueIdColumn {
+			return true
+		}
+	}
+	return false
+}
+
+// typeDescriptorKey returns a key for the type descriptor of table that is
+// not used by another type descriptor yet
+func typeDescriptorKey(d *descriptor.Descriptor, table string) string {
+	return unique(camelCase(table), func(key string) bool {
+		for _, td := range d.TypeDescriptors {
+			if td.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// fieldKey returns a key for the field of a column that is not used by
+// another field of td yet. The keys `id` and `name` are only used if they
+// are the unique ID column and the option name column of td, as required
+// by the descriptor.
+func fieldKey(td *descriptor.TypeDescriptor, column string) string {
+	key := camelCase(column)
+	if key == "id" && td.UniqueIdColumn != "id" ||
+		key == "name" && td.ColumnAsOptionName != "name" {
+		key = key + "Column"
+	}
+	return unique(key, func(key string) bool {
+		for _, field := range td.Fields {
+			if field.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func unique(key string, exists func(string) bool) string {
+	candidate := key
+	for i := 2; exists(candidate); i++ {
+		candidate = fmt.Sprintf("%s%d", key, i)
+	}
+	return candidate
+}
+
+// camelCase turns names like `purchase_date` or `PURCHASE DATE` into
+// `purchaseDate`
+func camelCase(name string) string {
+	var b strings.Builder
+	for i, word := range words(name) {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = upperFirst(word)
+		}
+		b.WriteString(word)
+	}
+	if b.Len() == 0 {
+		return "field"
+	}
+	return b.String()
+}
+
+// displayName turns names like `purchase_date` into `Purchase date`
+func displayName(name string) string {
+	display := strings.ToLower(strings.Join(words(name), " "))
+	if display == "" {
+		return name
+	}
+	return upperFirst(display)
+}
+
+func upperFirst(s string) string {
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// words splits a name at every character that is neither a letter nor a
+// digit, as well as at the humps of camel case names
+func words(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) && len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
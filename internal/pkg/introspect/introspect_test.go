@@ -0,0 +1,134 @@
+package introspect
+
+import (
+	"testing"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+var testTables = []*Table{
+	{
+		Name: "equipment",
+		Columns: []*Column{
+			{Name: "id", DataType: "INTEGER"},
+			{Name: "name", DataType: "TEXT"},
+			{Name: "purchase_date", DataType: "DATETIME"},
+		},
+		PrimaryKey: []string{"id"},
+	},
+	{
+		Name: "recipes",
+		Columns: []*Column{
+			{Name: "id", DataType: "integer"},
+			{Name: "equipment_id", DataType: "integer"},
+			{Name: "title", DataType: "character varying"},
+			{Name: "vegan", DataType: "boolean"},
+		},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []*ForeignKey{
+			{Name: "fk_equipment", Column: "equipment_id", ReferencedTable: "equipment"},
+		},
+	},
+}
+
+func TestMerge(t *testing.T) {
+	d := Merge(&descriptor.Descriptor{}, testTables)
+	if len(d.TypeDescriptors) != 2 {
+		t.Fatalf("Expected 2 type descriptors, got: %d", len(d.TypeDescriptors))
+	}
+	recipes := d.TypeDescriptors[1]
+	if recipes.Key != "recipes" || recipes.UniqueIdColumn != "id" || recipes.ColumnAsOptionName != "title" {
+		t.Errorf("Unexpected type descriptor: %+v", recipes)
+	}
+	expected := map[string]string{
+		"id":          "number",
+		"equipmentId": "number",
+		"title":       "text",
+		"vegan":       "boolean",
+		"equipment":   "text",
+	}
+	if len(recipes.Fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got: %d", len(expected), len(recipes.Fields))
+	}
+	for _, field := range recipes.Fields {
+		if expected[field.Key] != field.Type.Name {
+			t.Errorf("Expected field %s to be of type %s, got: %s", field.Key, expected[field.Key], field.Type.Name)
+		}
+	}
+	manyToOne := recipes.Fields[4].Relationship
+	if manyToOne == nil || manyToOne.Kind != "manyToOne" || manyToOne.ForeignTableUniqueIdColumn != "id" {
+		t.Errorf("Expected manyToOne relationship to equipment, got: %+v", manyToOne)
+	}
+	equipment := d.TypeDescriptors[0]
+	oneToMany := equipment.Fields[len(equipment.Fields)-1].Relationship
+	if oneToMany == nil || oneToMany.Kind != "oneToMany" || oneToMany.WithTable != "recipes" {
+		t.Errorf("Expected oneToMany relationship to recipes, got: %+v", oneToMany)
+	}
+}
+
+func TestMergeKeepsExistingFields(t *testing.T) {
+	d := &descriptor.Descriptor{
+		TypeDescriptors: []*descriptor.TypeDescriptor{{
+			Key:                "kitchenEquipment",
+			TableName:          "equipment",
+			UniqueIdColumn:     "id",
+			ColumnAsOptionName: "name",
+			Fields: []*descriptor.Field{{
+				Key:        "name",
+				Name:       "Equipment name",
+				FromColumn: "name",
+				Type:       &descriptor.WorkflowType{Name: "text", MultiLine: true},
+			}},
+		}},
+	}
+	Merge(d, testTables)
+	Merge(d, testTables)
+	equipment := d.TypeDescriptors[0]
+	if equipment.Key != "kitchenEquipment" || equipment.Fields[0].Name != "Equipment name" ||
+		!equipment.Fields[0].Type.MultiLine {
+		t.Errorf("Expected existing field to be kept, got: %+v", equipment.Fields[0])
+	}
+	// id, purchase_date and the relationship to recipes are added once
+	if len(equipment.Fields) != 4 {
+		t.Errorf("Expected 4 fields after merging twice, got: %d", len(equipment.Fields))
+	}
+	if len(d.TypeDescriptors) != 2 {
+		t.Errorf("Expected 2 type descriptors after merging twice, got: %d", len(d.TypeDescriptors))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	d := Merge(&descriptor.Descriptor{}, testTables)
+	if err := Validate(d, testTables); err != nil {
+		t.Fatalf("Expected generated descriptor to be valid, got: %v", err)
+	}
+	recipes := d.TypeDescriptors[1]
+	recipes.Fields[1].Type = &descriptor.WorkflowType{
+		Name:             "choice",
+		OptionsFromTable: &descriptor.OptionsFromTable{TableName: "equipment", IdColumn: "id", NameColumn: "label"},
+	}
+	recipes.Fields[2].FromColumn = "titel"
+	recipes.Fields[3].Type = &descriptor.WorkflowType{Name: "date", Kind: "date"}
+	recipes.Fields[4].Relationship.WithTable = "equipments"
+	d.TypeDescriptors = append(d.TypeDescriptors, &descriptor.TypeDescriptor{TableName: "missing"})
+	err := Validate(d, testTables)
+	problems, ok := err.(Problems)
+	if !ok {
+		t.Fatalf("Expected problems, got: %v", err)
+	}
+	expected := []string{
+		"$.typeDescriptors[1].fields[1].type.optionsFromTable.nameColumn: column 'label' does not exist in table 'equipment'",
+		"$.typeDescriptors[1].fields[2].fromColumn: column 'titel' does not exist in table 'recipes'",
+		"$.typeDescriptors[1].fields[3].fromColumn: column 'vegan' of type boolean is not compatible with the workflow type date",
+		"$.typeDescriptors[1].fields[4].relationship.withTable: table 'equipments' does not exist",
+		"$.typeDescriptors[2].tableName: table 'missing' does not exist",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got: %v", len(expected), err)
+	}
+	for i, problem := range problems {
+		if problem.Error() != expected[i] {
+			t.Errorf("Expected problem %q, got: %q", expected[i], problem.Error())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package introspect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+// Problem is a mismatch between the descriptor and the database schema.
+// Path is the JSON path of the offending property in descriptor.json.
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Problems are all mismatches between the descriptor and the database
+// schema found by Validate
+type Problems []*Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.Error()
+	}
+	return fmt.Sprintf(
+		"descriptor.json does not match the database schema:\n%s",
+		strings.Join(lines, "\n"),
+	)
+}
+
+// Validate checks that every table and column referenced by the type
+// descriptors of d exists in the database schema, and that the data type
+// of every column is compatible with the workflow type of its field. Type
+// descriptors backed by a view or a query are skipped. All problems found
+// are returned at once as Problems.
+func Validate(d *descriptor.Descriptor, tables []*Table) error {
+	v := &validator{tables: tables}
+	for i, td := range d.TypeDescriptors {
+		if td.ReadOnly() {
+			// The columns of views and queries are only known once they
+			// are queried, which happens when their schema is saved
+			continue
+		}
+		path := fmt.Sprintf("$.typeDescriptors[%d]", i)
+		table := v.table(path+".tableName", td.TableName)
+		if table == nil {
+			continue
+		}
+		v.column(path+".uniqueIdColumn", table, td.UniqueIdColumn)
+		if td.ColumnAsOptionName != "" {
+			v.column(path+".columnAsOptionName", table, td.ColumnAsOptionName)
+		}
+		for j, field := range td.Fields {
+			v.field(fmt.Sprintf("%s.fields[%d]", path, j), table, field)
+		}
+	}
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+	return nil
+}
+
+type validator struct {
+	tables   []*Table
+	problems Problems
+}
+
+func (v *validator) report(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) table(path, name string) *Table {
+	if name == "" {
+		v.report(path, "table name is missing")
+		return nil
+	}
+	for _, table := range v.tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	v.report(path, "table '%s' does not exist", name)
+	return nil
+}
+
+func (v *validator) column(path string, table *Table, name string) *Column {
+	if name == "" {
+		v.report(path, "column name is missing")
+		return nil
+	}
+	for _, column := range table.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column
+		}
+	}
+	v.report(path, "column '%s' does not exist in table '%s'", name, table.Name)
+	return nil
+}
+
+func (v *validator) field(path string, table *Table, field *descriptor.Field) {
+	if field.Relationship != nil {
+		r := field.Relationship
+		v.column(path+".relationship.localTableUniqueIdColumn", table, r.LocalTableUniqueIdColumn)
+		if withTable := v.table(path+".relationship.withTable", r.WithTable); withTable != nil {
+			v.column(path+".relationship.foreignTableUniqueIdColumn", withTable, r.ForeignTableUniqueIdColumn)
+		}
+		return
+	}
+	if field.Type == nil {
+		return
+	}
+	if field.Type.Name == "money" {
+		if amount := field.Type.Amount; amount != nil && amount.FromColumn != "" {
+			if column := v.column(path+".type.amount.fromColumn", table, amount.FromColumn); column != nil {
+				v.compatible(path+".type.amount.fromColumn", column, "number")
+			}
+		}
+		if currency := field.Type.Currency; currency != nil && currency.FromColumn != "" {
+			if column := v.column(path+".type.currency.fromColumn", table, currency.FromColumn); column != nil {
+				v.compatible(path+".type.currency.fromColumn", column, "text")
+			}
+		}
+		return
+	}
+	if lookup := field.Type.OptionsFromTable; lookup != nil {
+		if lookupTable := v.table(path+".type.optionsFromTable.tableName", lookup.TableName); lookupTable != nil {
+			v.column(path+".type.optionsFromTable.idColumn", lookupTable, lookup.IdColumn)
+			v.column(path+".type.optionsFromTable.nameColumn", lookupTable, lookup.NameColumn)
+		}
+	}
+	if field.FromColumn == "" {
+		return
+	}
+	if len(field.Expression) > 0 {
+		// The column of a computed field is added by its expression and
+		// must not shadow a column of the table
+		for _, column := range table.Columns {
+			if strings.EqualFold(column.Name, field.FromColumn) {
+				v.report(
+					path+".fromColumn", "computed field can not use the name of the existing column '%s' in table '%s'",
+					column.Name, table.Name,
+				)
+			}
+		}
+		return
+	}
+	if column := v.column(path+".fromColumn", table, field.FromColumn); column != nil {
+		v.compatible(path+".fromColumn", column, field.Type.Name)
+	}
+}
+
+// compatibleCategories lists the categories of column data types that can
+// hold the values of a workflow type. Workflow types that are missing can
+// be read from any column.
+var compatibleCategories = map[string][]string{
+	"number": {"number"},
+	// Booleans are commonly stored as small integers or bits
+	"boolean": {"boolean", "number"},
+	// Dates may be stored as ISO 8601 strings, for example in SQLite
+	"date": {"date", "text"},
+}
+
+func (v *validator) compatible(path string, column *Column, workflowType string) {
+	categories, ok := compatibleCategories[workflowType]
+	if !ok {
+		return
+	}
+	actual := category(column.DataType)
+	if actual == "" {
+		return
+	}
+	for _, c := range categories {
+		if c == actual {
+			return
+		}
+	}
+	v.report(
+		path, "column '%s' of type %s is not compatible with the workflow type %s",
+		column.Name, column.DataType, workflowType,
+	)
+}
@@ -1,37 +1,236 @@
+// Package log provides a leveled logger that writes structured log
+// entries as text or JSON. Entries written while handling a request
+// carry the request's correlation ID.
 package log
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
-type Logger bool
+type Level int
 
-func When(isEnabled bool) Logger {
-	if isEnabled {
-		return Logger(true)
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+	FatalLevel: "fatal",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the given name
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
 	}
-	return Logger(false)
+	return InfoLevel, fmt.Errorf("unknown log level %q", name)
 }
-func (l Logger) Infof(format string, v ...interface{}) {
-	if l {
-		fmt.Printf(format, v...)
+
+// Entry is a single log entry
+type Entry struct {
+	Time      time.Time
+	Level     Level
+	Component string
+	RequestID string
+	Message   string
+}
+
+// Encoder writes log entries to w
+type Encoder func(w io.Writer, e *Entry) error
+
+// TextEncoder writes entries as human readable lines
+func TextEncoder(w io.Writer, e *Entry) error {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	fmt.Fprintf(&b, " %-5s", strings.ToUpper(e.Level.String()))
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " request_id=%s", e.RequestID)
+	}
+	if e.Component != "" {
+		fmt.Fprintf(&b, " [%s]", e.Component)
+	}
+	if e.Message != "" {
+		b.WriteString(" ")
+		b.WriteString(e.Message)
 	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
 }
-func (l Logger) Infoln(v ...interface{}) {
-	if l {
-		fmt.Println(v...)
+
+// JSONEncoder writes entries as JSON lines
+func JSONEncoder(w io.Writer, e *Entry) error {
+	line, err := json.Marshal(struct {
+		Time      string `json:"time"`
+		Level     string `json:"level"`
+		Component string `json:"component,omitempty"`
+		RequestID string `json:"request_id,omitempty"`
+		Message   string `json:"msg"`
+	}{
+		Time:      e.Time.Format(time.RFC3339Nano),
+		Level:     e.Level.String(),
+		Component: e.Component,
+		RequestID: e.RequestID,
+		Message:   e.Message,
+	})
+	if err != nil {
+		return err
 	}
+	_, err = w.Write(append(line, '\n'))
+	return err
 }
-func (l Logger) Fatalln(v ...interface{}) {
-	if l {
-		fmt.Println(v...)
-		os.Exit(1)
+
+var std = struct {
+	sync.Mutex
+	out     io.Writer
+	level   Level
+	encoder Encoder
+}{
+	out:     os.Stdout,
+	level:   InfoLevel,
+	encoder: TextEncoder,
+}
+
+// Configure sets the minimum level of the entries that are written and
+// the format, either `text` or `json`, they are written in
+func Configure(level, format string) error {
+	lvl := InfoLevel
+	if level != "" {
+		var err error
+		if lvl, err = ParseLevel(level); err != nil {
+			return err
+		}
+	}
+	var encoder Encoder
+	switch strings.ToLower(format) {
+	case "", "text":
+		encoder = TextEncoder
+	case "json":
+		encoder = JSONEncoder
+	default:
+		return fmt.Errorf("unknown log format %q", format)
 	}
+	std.Lock()
+	defer std.Unlock()
+	std.level = lvl
+	std.encoder = encoder
+	return nil
+}
+
+// SetOutput sets the destination of all log entries
+func SetOutput(w io.Writer) {
+	std.Lock()
+	defer std.Unlock()
+	std.out = w
+}
+
+type contextKey string
+
+const requestIDKey = contextKey("requestID")
+
+// WithRequestID returns a copy of ctx carrying the request's correlation ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the correlation ID of the request ctx belongs to
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// Logger writes debug and info entries only if it is enabled, while
+// warnings, errors and fatal errors are always written
+type Logger struct {
+	enabled   bool
+	requestID string
+}
+
+func When(isEnabled bool) Logger {
+	return Logger{enabled: isEnabled}
+}
+
+// WithContext returns a logger whose entries carry the correlation ID of
+// the request ctx belongs to
+func (l Logger) WithContext(ctx context.Context) Logger {
+	l.requestID = RequestID(ctx)
+	return l
+}
+
+func (l Logger) Debugf(format string, v ...interface{}) {
+	l.write(DebugLevel, fmt.Sprintf(format, v...))
+}
+func (l Logger) Debugln(v ...interface{}) {
+	l.write(DebugLevel, fmt.Sprintln(v...))
+}
+func (l Logger) Infof(format string, v ...interface{}) {
+	l.write(InfoLevel, fmt.Sprintf(format, v...))
+}
+func (l Logger) Infoln(v ...interface{}) {
+	l.write(InfoLevel, fmt.Sprintln(v...))
+}
+func (l Logger) Warnf(format string, v ...interface{}) {
+	l.write(WarnLevel, fmt.Sprintf(format, v...))
+}
+func (l Logger) Errorf(format string, v ...interface{}) {
+	l.write(ErrorLevel, fmt.Sprintf(format, v...))
+}
+func (l Logger) Fatalln(v ...interface{}) {
+	l.write(FatalLevel, fmt.Sprintln(v...))
+	os.Exit(1)
 }
 func (l Logger) Fatalf(format string, v ...interface{}) {
-	if l {
-		fmt.Printf(format, v...)
-		os.Exit(1)
+	l.write(FatalLevel, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+// component matches the `[handler -> db]` like prefix of log messages
+var component = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+
+func (l Logger) write(level Level, msg string) {
+	if level < WarnLevel && !l.enabled {
+		return
+	}
+	std.Lock()
+	defer std.Unlock()
+	if level < std.level {
+		return
+	}
+	entry := &Entry{
+		Time:      time.Now().UTC(),
+		Level:     level,
+		RequestID: l.requestID,
+		Message:   strings.TrimRight(msg, "\n"),
+	}
+	if matches := component.FindStringSubmatch(entry.Message); matches != nil {
+		entry.Component = matches[1]
+		entry.Message = entry.Message[len(matches[0]):]
+	}
+	if err := std.encoder(std.out, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write log entry: %s\n", err)
 	}
 }
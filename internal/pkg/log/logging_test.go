@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer Configure("info", "text")
+	ctx := WithRequestID(context.Background(), "4f2a9c")
+	t.Run("json encoder", func(t *testing.T) {
+		out.Reset()
+		if err := Configure("info", "json"); err != nil {
+			t.Fatalf("Expected no error, instead got: '%v'", err)
+		}
+		When(true).WithContext(ctx).Infof("[handler] %s\n", "GetSingle")
+		entry := make(map[string]string)
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatalf("Expected a JSON log entry, instead got: '%s'", out.String())
+		}
+		want := map[string]string{
+			"level":      "info",
+			"component":  "handler",
+			"request_id": "4f2a9c",
+			"msg":        "GetSingle",
+		}
+		for key, value := range want {
+			if entry[key] != value {
+				t.Errorf("Expected %s to be '%s', got: '%s'", key, value, entry[key])
+			}
+		}
+	})
+	t.Run("text encoder", func(t *testing.T) {
+		out.Reset()
+		if err := Configure("debug", "text"); err != nil {
+			t.Fatalf("Expected no error, instead got: '%v'", err)
+		}
+		When(true).WithContext(ctx).Debugln("[handler -> db] get query results")
+		want := "DEBUG request_id=4f2a9c [handler -> db] get query results\n"
+		if !strings.HasSuffix(out.String(), want) {
+			t.Errorf("Expected a line ending in '%s', got: '%s'", want, out.String())
+		}
+	})
+	t.Run("levels", func(t *testing.T) {
+		out.Reset()
+		if err := Configure("warn", "text"); err != nil {
+			t.Fatalf("Expected no error, instead got: '%v'", err)
+		}
+		When(true).Infoln("[handler] filtered by level")
+		if out.Len() != 0 {
+			t.Errorf("Expected info entries to be filtered, got: '%s'", out.String())
+		}
+		Configure("debug", "text")
+		When(false).Infoln("[handler] disabled")
+		if out.Len() != 0 {
+			t.Errorf("Expected a disabled logger to omit info entries, got: '%s'", out.String())
+		}
+		When(false).Errorf("[backend] unable to open connection\n")
+		if !strings.Contains(out.String(), "ERROR [backend] unable to open connection") {
+			t.Errorf("Expected errors to be written by a disabled logger, got: '%s'", out.String())
+		}
+		if err := Configure("verbose", "text"); err == nil {
+			t.Errorf("Expected an error for an unknown log level")
+		}
+		if err := Configure("info", "xml"); err == nil {
+			t.Errorf("Expected an error for an unknown log format")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+// Package metrics defines the Prometheus metrics exposed by the workflow
+// connector
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "workflow_connector"
+
+// Registry holds all metrics of the workflow connector
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Requests counts the handled requests by route name, type
+	// descriptor key and status code
+	Requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		},
+		[]string{"route", "type_descriptor", "code"},
+	)
+	// RequestDuration observes the time taken to handle requests by
+	// route name and type descriptor key
+	RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "type_descriptor"},
+	)
+	// QueryDuration observes the time taken by database queries by the
+	// name of the query template
+	QueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time taken to execute database queries.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"template"},
+	)
+	// RateLimitEvents counts the requests throttled or locked out by the
+	// rate limiter, and the failed authentications it tracks, by event
+	RateLimitEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_events_total",
+			Help:      "Number of requests throttled or locked out by the rate limiter.",
+		},
+		[]string{"event"},
+	)
+	// AuthFailures counts the requests that failed to authenticate
+	AuthFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_failures_total",
+			Help:      "Number of requests that failed HTTP Basic authentication.",
+		},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		// The audit log counts its events as expvars
+		collectors.NewExpvarCollector(map[string]*prometheus.Desc{
+			"audit": prometheus.NewDesc(
+				namespace+"_audit_records_total",
+				"Number of audit records by the result of writing them.",
+				[]string{"result"},
+				nil,
+			),
+		}),
+		Requests,
+		RequestDuration,
+		QueryDuration,
+		RateLimitEvents,
+		AuthFailures,
+	)
+}
+
+// RegisterDB registers gauges for the connection pool statistics of db
+// and the number of open transactions reported by openTransactions
+func RegisterDB(db *sql.DB, dbName string, openTransactions func() float64) error {
+	if err := Registry.Register(collectors.NewDBStatsCollector(db, dbName)); err != nil {
+		return err
+	}
+	return Registry.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_open_transactions",
+			Help:      "Number of transactions opened by clients and not yet committed.",
+		},
+		openTransactions,
+	))
+}
+
+// ObserveQuery records the duration of a query executed using the given
+// query template that was started at start
+func ObserveQuery(template string, start time.Time) {
+	QueryDuration.WithLabelValues(template).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
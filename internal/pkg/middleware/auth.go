@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
@@ -9,13 +10,23 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
 	"golang.org/x/crypto/argon2"
 )
 
 var ErrUnauthorized = errors.New("error: unable to authorize user")
 var RealmMessage = `Authentication required for API access to workflow db connector`
 
+// unauthenticatedRoutes can be accessed without credentials, so that
+// orchestrators can probe the health of the workflow connector
+var unauthenticatedRoutes = map[string]bool{
+	"Healthz": true,
+	"Readyz":  true,
+}
+
 type keyDerivationFn interface {
 	Key(password, salt []byte) ([]byte, error)
 	ParsePHCString(PHCStringHash string) (digest, salt []byte, err error)
@@ -33,8 +44,13 @@ type argon2Kdf struct {
 // and returns a negroni middleware implementing HTTP Basic Authentication
 func BasicAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && unauthenticatedRoutes[route.GetName()] {
+			next.ServeHTTP(w, r)
+			return
+		}
 		username, password, ok := r.BasicAuth()
-		storedUsername, storedPasswordHash := getStoredUsernamePassword(config.Options)
+		user := getStoredUser(*config.Current(r.Context()), username)
+		storedUsername, storedPasswordHash := user.Username, user.PasswordHash
 		kdf, err := selectKdf(storedPasswordHash)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -52,11 +68,23 @@ func BasicAuth(next http.Handler) http.Handler {
 		}
 		if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(storedUsername)) != 1 ||
 			subtle.ConstantTimeCompare(digest, storedDigest) != 1 {
+			metrics.AuthFailures.Inc()
 			w.Header().Set("WWW-Authenticate", "Basic realm="+RealmMessage)
 			http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		if authenticated, ok := r.Context().Value(util.ContextKey("authenticated")).(*bool); ok {
+			*authenticated = true
+		}
+		withPrincipal := context.WithValue(
+			r.Context(),
+			util.ContextKey("principal"),
+			&util.Principal{
+				Username:   user.Username,
+				Attributes: user.Attributes,
+			},
+		)
+		next.ServeHTTP(w, r.WithContext(withPrincipal))
 	})
 }
 
@@ -114,6 +142,19 @@ func (a *argon2Kdf) Key(password, salt []byte) ([]byte, error) {
 	return digest, nil
 }
 
-func getStoredUsernamePassword(cfg config.Config) (username, passwordHash string) {
-	return cfg.Auth.Username, cfg.Auth.PasswordHash
+// getStoredUser returns the user with the given username. If no such
+// user exists, the user defined in the `auth` section of the config file
+// is returned so that the client's password is still hashed and compared
+// and the response time does not reveal whether the username exists.
+func getStoredUser(cfg config.Config, username string) *config.User {
+	for _, user := range cfg.Auth.Users {
+		if subtle.ConstantTimeCompare([]byte(username), []byte(user.Username)) == 1 {
+			return user
+		}
+	}
+	return &config.User{
+		Username:     cfg.Auth.Username,
+		PasswordHash: cfg.Auth.PasswordHash,
+		Attributes:   cfg.Auth.Attributes,
+	}
 }
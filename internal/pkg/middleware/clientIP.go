@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+)
+
+// clientIP returns the IP address of the client that sent the request.
+// The `Forwarded` and `X-Forwarded-For` headers are only taken into
+// account when the request was received from a trusted proxy, in which
+// case the chain of forwarding proxies is walked from right to left and
+// the first address that does not belong to a trusted proxy is returned.
+func clientIP(r *http.Request) string {
+	remote := remoteIP(r)
+	trustedProxies, _ := config.ParseNetworks(config.Current(r.Context()).TrustedProxies)
+	if len(trustedProxies) == 0 || !containsIP(trustedProxies, net.ParseIP(remote)) {
+		return remote
+	}
+	chain := forwardedFor(r)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(chain[i])
+		if ip == nil {
+			// An address we can not parse (ie. `unknown` or an obfuscated
+			// identifier) ends the chain of proxies we are able to trust
+			return remote
+		}
+		if !containsIP(trustedProxies, ip) {
+			return ip.String()
+		}
+		remote = ip.String()
+	}
+	return remote
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the chain of client addresses contained in the
+// `Forwarded` header as specified in RFC 7239, falling back to the
+// `X-Forwarded-For` header if the former is not present
+func forwardedFor(r *http.Request) (chain []string) {
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, header := range forwarded {
+			for _, element := range strings.Split(header, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+					if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+						chain = append(chain, parseForwardedNode(kv[1]))
+					}
+				}
+			}
+		}
+		return chain
+	}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(header, ",") {
+			chain = append(chain, strings.TrimSpace(ip))
+		}
+	}
+	return chain
+}
+
+// parseForwardedNode strips the quotes, brackets and port that may
+// surround an address in the `for` parameter of the `Forwarded` header,
+// for example `"[2001:db8:cafe::17]:4711"` or `192.0.2.60:8080`
+func parseForwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -26,11 +26,11 @@ func RequestInjector(next http.Handler) http.Handler {
 		// and *not* the name of the table in the database
 		typeDescriptorKey := mux.Vars(r)["table"]
 		tableName, _ := util.GetDBTableNameUsingTypeDescriptorKey(
-			config.Options.Descriptor.TypeDescriptors,
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
 			typeDescriptorKey,
 		)
 		typeDescriptor := util.GetTypeDescriptorUsingTypeDescriptorKey(
-			config.Options.Descriptor.TypeDescriptors,
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
 			typeDescriptorKey,
 		)
 		withCurrentRoute := context.WithValue(
@@ -73,12 +73,21 @@ func RequestInjector(next http.Handler) http.Handler {
 			util.ContextKey("$denormalize"),
 			denormalize,
 		)
-		newReq := r.WithContext(withDenormalize)
-		// TODO
-		//contextWithCancel, cancelFn := context.WithCancel(contextWithRelationships)
-
-		//return req.WithContext(contextWithCancel), cancelFn
-		next.ServeHTTP(w, newReq)
+		withRowFilter := context.WithValue(
+			withDenormalize,
+			util.ContextKey("rowFilter"),
+			typeDescriptor.RowFilter,
+		)
+		ctx := withRowFilter
+		// Queries are cancelled if the database does not answer within
+		// the statement timeout, so that a hung query does not hold on
+		// to a connection forever
+		if timeout := config.Current(r.Context()).Database.StatementTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -86,7 +95,7 @@ func RequestInjector(next http.Handler) http.Handler {
 func ResponseInjector(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		if config.Options.TLS.Enabled {
+		if config.Current(r.Context()).TLS.Enabled {
 			w.Header().Add("Strict-Transport-Security",
 				"max-age=63072000; includeSubDomains")
 		}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// IPAllowlist rejects requests from clients whose IP address is not part
+// of the networks listed in the `ipAllowlist` section of the config file.
+// If the requested route belongs to a type descriptor that has its own
+// list of networks, the client must be part of those networks as well.
+func IPAllowlist(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowlist := config.Current(r.Context()).IPAllowlist
+		if !allowlist.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := clientIP(r)
+		ip := net.ParseIP(client)
+		if !isAllowed(ip, allowlist.Networks) {
+			forbidden(w, r, client)
+			return
+		}
+		typeDescriptorKey := mux.Vars(r)["table"]
+		for _, td := range allowlist.TypeDescriptors {
+			if td.Key == typeDescriptorKey && !isAllowed(ip, td.Networks) {
+				forbidden(w, r, client)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAllowed reports whether ip is part of one of the networks. An empty
+// list of networks does not restrict access.
+func isAllowed(ip net.IP, networks []string) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	// The networks have already been validated when parsing the config file
+	parsed, _ := config.ParseNetworks(networks)
+	return containsIP(parsed, ip)
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request, client string) {
+	log.When(config.Logging()).WithContext(r.Context()).Infof(
+		"[middleware] client %s is not part of the allowed networks\n",
+		client,
+	)
+	msg := util.NewError(
+		util.ErrorCodeForbidden,
+		fmt.Sprintf("access denied for client %s", client),
+	)
+	http.Error(w, msg.String(), msg.Code)
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+)
+
+func TestClientIP(t *testing.T) {
+	config.Options.TrustedProxies = []string{"10.0.0.0/8"}
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{
+			name:       "headers of untrusted clients are ignored",
+			remoteAddr: "192.0.2.1:4242",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "X-Forwarded-For of a trusted proxy is used",
+			remoteAddr: "10.0.0.1:4242",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7, 10.0.0.2"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Forwarded takes precedence over X-Forwarded-For",
+			remoteAddr: "10.0.0.1:4242",
+			header: http.Header{
+				"Forwarded":       {`for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "unparseable addresses end the chain",
+			remoteAddr: "10.0.0.1:4242",
+			header:     http.Header{"Forwarded": {"for=unknown"}},
+			want:       "10.0.0.1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header = tc.header
+			if got := clientIP(req); got != tc.want {
+				t.Errorf("Expected: '%s', got: '%s'", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIPAllowlist(t *testing.T) {
+	config.Options.TrustedProxies = nil
+	config.Options.IPAllowlist = config.IPAllowlist{
+		Enabled:  true,
+		Networks: []string{"10.0.0.0/8"},
+		TypeDescriptors: []*config.TypeDescriptorNetworks{
+			{Key: "equipment", Networks: []string{"10.1.0.0/16"}},
+		},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/{table}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Use(IPAllowlist)
+	testCases := []struct {
+		remoteAddr string
+		path       string
+		want       int
+	}{
+		{"10.2.0.1:4242", "/recipes", http.StatusOK},
+		{"10.1.0.1:4242", "/equipment", http.StatusOK},
+		{"10.2.0.1:4242", "/equipment", http.StatusForbidden},
+		{"192.0.2.1:4242", "/recipes", http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.RemoteAddr = tc.remoteAddr
+		router.ServeHTTP(res, req)
+		if res.Code != tc.want {
+			t.Errorf("%s %s: expected: '%d', got: '%d'", tc.remoteAddr, tc.path, tc.want, res.Code)
+		}
+	}
+}
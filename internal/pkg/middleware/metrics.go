@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// Metrics counts the handled requests and observes their duration, labeled
+// by the name of the route and the key of the requested type descriptor
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		var routeName string
+		if route := mux.CurrentRoute(r); route != nil {
+			routeName = route.GetName()
+		}
+		// Only keys of existing type descriptors are used as label values,
+		// so that clients can not create an unbounded number of time series
+		var typeDescriptorKey string
+		if td := util.GetTypeDescriptorUsingTypeDescriptorKey(
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
+			mux.Vars(r)["table"],
+		); td != nil {
+			typeDescriptorKey = td.Key
+		}
+		metrics.Requests.WithLabelValues(
+			routeName,
+			typeDescriptorKey,
+			strconv.Itoa(rec.status),
+		).Inc()
+		metrics.RequestDuration.WithLabelValues(
+			routeName,
+			typeDescriptorKey,
+		).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	config.Options.Descriptor = &descriptor.Descriptor{
+		TypeDescriptors: []*descriptor.TypeDescriptor{{Key: "equipment"}},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/{table}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Name("GetCollection")
+	router.Use(Metrics)
+	for _, path := range []string{"/equipment", "/equipment", "/doesNotExist"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if got := testutil.ToFloat64(metrics.Requests.WithLabelValues("GetCollection", "equipment", "404")); got != 2 {
+		t.Errorf("Expected 2 requests for type descriptor `equipment`, got: %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.Requests.WithLabelValues("GetCollection", "", "404")); got != 1 {
+		t.Errorf("Expected unknown type descriptor keys to be omitted from labels, got: %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// limiter holds the state shared by all requests passing through the
+// RateLimit middleware. It is initialized lazily from the options of the
+// first request.
+var (
+	limiter     *rateLimiter
+	limiterOnce sync.Once
+)
+
+type rateLimiter struct {
+	cfg        config.RateLimit
+	mu         sync.Mutex
+	byClient   map[string]*tokenBucket
+	byUsername map[string]*tokenBucket
+	// failures are counted per client IP address only, so that an
+	// unauthenticated client can not lock out a username for everyone
+	failures map[string]*failureRecord
+	now      func() time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	lastSeen time.Time
+}
+
+type failureRecord struct {
+	count       int
+	lockedUntil time.Time
+	lastSeen    time.Time
+}
+
+// statusRecorder remembers the status code written by the next handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// RateLimit throttles requests per client IP address and per username
+// using token buckets. Client IP addresses that repeatedly fail to
+// authenticate are locked out with an exponentially increasing delay. Throttled requests
+// are answered with 429 Too Many Requests and a Retry-After header before
+// any password hashing is performed by the BasicAuth middleware.
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !config.Current(r.Context()).RateLimit.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limiterOnce.Do(func() {
+			limiter = newRateLimiter(config.Current(r.Context()).RateLimit)
+		})
+		client := clientIP(r)
+		username, _, _ := r.BasicAuth()
+		if retryAfter, ok := limiter.allow(client, username); !ok {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+		// The BasicAuth middleware reports a successful authentication,
+		// since unauthenticated routes like /healthz must not reset the
+		// failures of a client
+		authenticated := new(bool)
+		r = r.WithContext(context.WithValue(r.Context(), util.ContextKey("authenticated"), authenticated))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.status == http.StatusUnauthorized {
+			metrics.RateLimitEvents.WithLabelValues("auth_failure").Inc()
+			limiter.recordFailure(client)
+			return
+		}
+		if *authenticated {
+			limiter.recordSuccess(client)
+		}
+	})
+}
+
+func newRateLimiter(cfg config.RateLimit) *rateLimiter {
+	return &rateLimiter{
+		cfg:        cfg,
+		byClient:   make(map[string]*tokenBucket),
+		byUsername: make(map[string]*tokenBucket),
+		failures:   make(map[string]*failureRecord),
+		now:        time.Now,
+	}
+}
+
+// allow reports whether the request should be passed on to the next
+// handler, and if not, how long the client should wait before retrying
+func (l *rateLimiter) allow(client, username string) (retryAfter time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.evictStale(now)
+	if f, exists := l.failures[client]; exists && now.Before(f.lockedUntil) {
+		metrics.RateLimitEvents.WithLabelValues("locked_out").Inc()
+		return f.lockedUntil.Sub(now), false
+	}
+	if wait, ok := take(l.byClient, client, l.cfg.PerClient, now); !ok {
+		metrics.RateLimitEvents.WithLabelValues("throttled_client").Inc()
+		return wait, false
+	}
+	if username == "" {
+		return 0, true
+	}
+	if wait, ok := take(l.byUsername, username, l.cfg.PerUsername, now); !ok {
+		metrics.RateLimitEvents.WithLabelValues("throttled_username").Inc()
+		return wait, false
+	}
+	return 0, true
+}
+
+func (l *rateLimiter) recordFailure(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	f, exists := l.failures[client]
+	if !exists {
+		f = &failureRecord{}
+		l.failures[client] = f
+	}
+	f.count++
+	f.lastSeen = now
+	if l.cfg.Lockout.Threshold > 0 && f.count >= l.cfg.Lockout.Threshold {
+		f.lockedUntil = now.Add(l.lockoutDelay(f.count))
+	}
+}
+
+func (l *rateLimiter) recordSuccess(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, client)
+}
+
+// lockoutDelay doubles the base delay for every failure that exceeds the
+// configured threshold, up to the configured maximum delay
+func (l *rateLimiter) lockoutDelay(failures int) time.Duration {
+	exponent := failures - l.cfg.Lockout.Threshold
+	delay := float64(l.cfg.Lockout.BaseDelay) * math.Pow(2, float64(exponent))
+	if l.cfg.Lockout.MaxDelay > 0 && delay > float64(l.cfg.Lockout.MaxDelay) {
+		return l.cfg.Lockout.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// evictStale removes buckets and failure records which have not been
+// used for a while, so that the limiter does not grow without bounds
+func (l *rateLimiter) evictStale(now time.Time) {
+	ttl := l.cfg.Lockout.MaxDelay
+	if ttl < time.Hour {
+		ttl = time.Hour
+	}
+	for _, buckets := range []map[string]*tokenBucket{l.byClient, l.byUsername} {
+		for key, b := range buckets {
+			if now.Sub(b.lastSeen) > ttl {
+				delete(buckets, key)
+			}
+		}
+	}
+	for key, f := range l.failures {
+		if now.Sub(f.lastSeen) > ttl && now.After(f.lockedUntil) {
+			delete(l.failures, key)
+		}
+	}
+}
+
+// take removes a token from the bucket stored under key, refilling it
+// first according to the time elapsed since it was last used. A bucket
+// with a non-positive rate is treated as unlimited.
+func take(buckets map[string]*tokenBucket, key string, cfg config.TokenBucket, now time.Time) (time.Duration, bool) {
+	if cfg.Rate <= 0 {
+		return 0, true
+	}
+	burst := float64(cfg.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b, exists := buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: burst, last: now}
+		buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*cfg.Rate)
+	b.last = now
+	b.lastSeen = now
+	if b.tokens < 1 {
+		missing := (1 - b.tokens) / cfg.Rate
+		return time.Duration(missing * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	msg := util.NewError(util.ErrorCodeRateLimited, fmt.Sprintf(
+		"too many requests, please retry after %d seconds",
+		seconds,
+	))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, msg.String(), msg.Code)
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+func TestRateLimit(t *testing.T) {
+	cfg := config.RateLimit{
+		Enabled:     true,
+		PerClient:   config.TokenBucket{Rate: 1, Burst: 2},
+		PerUsername: config.TokenBucket{Rate: 1, Burst: 2},
+		Lockout: config.Lockout{
+			Threshold: 2,
+			BaseDelay: time.Second,
+			MaxDelay:  4 * time.Second,
+		},
+	}
+	t.Run("token buckets", func(t *testing.T) {
+		now := time.Now()
+		l := newRateLimiter(cfg)
+		l.now = func() time.Time { return now }
+		t.Run("success cases", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if _, ok := l.allow("10.0.0.1", "wfauser"); !ok {
+					t.Errorf("Expected request %d to be allowed", i)
+				}
+			}
+			now = now.Add(time.Second)
+			if _, ok := l.allow("10.0.0.1", ""); !ok {
+				t.Errorf("Expected bucket to be refilled after one second")
+			}
+		})
+		t.Run("failure cases", func(t *testing.T) {
+			retryAfter, ok := l.allow("10.0.0.1", "")
+			if ok {
+				t.Errorf("Expected request to be throttled")
+			}
+			if retryAfter <= 0 || retryAfter > time.Second {
+				t.Errorf("Expected a retry after of at most one second, instead got: '%v'", retryAfter)
+			}
+		})
+	})
+	t.Run("lockout", func(t *testing.T) {
+		now := time.Now()
+		l := newRateLimiter(cfg)
+		l.now = func() time.Time { return now }
+		t.Run("failure cases", func(t *testing.T) {
+			l.recordFailure("10.0.0.2")
+			if _, ok := l.allow("10.0.0.2", "wfauser"); !ok {
+				t.Errorf("Expected no lockout below the threshold")
+			}
+			l.recordFailure("10.0.0.2")
+			retryAfter, ok := l.allow("10.0.0.2", "wfauser")
+			if ok {
+				t.Errorf("Expected client to be locked out")
+			}
+			if retryAfter != time.Second {
+				t.Errorf("Expected: '%v', got: '%v'", time.Second, retryAfter)
+			}
+			if _, ok := l.allow("10.0.0.3", "wfauser"); !ok {
+				t.Errorf("Expected the username not to be locked out for other clients")
+			}
+			for i := 0; i < 4; i++ {
+				l.recordFailure("10.0.0.2")
+			}
+			if got := l.failures["10.0.0.2"].lockedUntil.Sub(now); got != 4*time.Second {
+				t.Errorf("Expected lockout to be capped at: '%v', got: '%v'", 4*time.Second, got)
+			}
+		})
+		t.Run("success cases", func(t *testing.T) {
+			l.recordSuccess("10.0.0.2")
+			// Refill the token bucket of the username
+			now = now.Add(time.Second)
+			if _, ok := l.allow("10.0.0.2", "wfauser"); !ok {
+				t.Errorf("Expected lockout to be lifted after a successful login")
+			}
+		})
+	})
+	t.Run("middleware", func(t *testing.T) {
+		config.Options.RateLimit = cfg
+		limiter = newRateLimiter(cfg)
+		limiterOnce.Do(func() {})
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		handler := RateLimit(next)
+		var res *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			res = httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/equipment", nil)
+			req.RemoteAddr = "10.0.0.4:4242"
+			handler.ServeHTTP(res, req)
+		}
+		if res.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected: '%d', got: '%d'", http.StatusTooManyRequests, res.Code)
+		}
+		if res.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected Retry-After header to be '1', got: '%s'", res.Header().Get("Retry-After"))
+		}
+	})
+	t.Run("failures are only reset after authentication", func(t *testing.T) {
+		config.Options.RateLimit = cfg
+		limiter = newRateLimiter(cfg)
+		limiterOnce.Do(func() {})
+		unauthenticated := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		authenticated := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*r.Context().Value(util.ContextKey("authenticated")).(*bool) = true
+			w.WriteHeader(http.StatusOK)
+		}))
+		limiter.recordFailure("10.0.0.5")
+		serve := func(handler http.Handler, path string) {
+			req := httptest.NewRequest("GET", path, nil)
+			req.RemoteAddr = "10.0.0.5:4242"
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		serve(unauthenticated, "/healthz")
+		if _, exists := limiter.failures["10.0.0.5"]; !exists {
+			t.Errorf("Expected failures to be kept after an unauthenticated request")
+		}
+		serve(authenticated, "/equipment")
+		if _, exists := limiter.failures["10.0.0.5"]; exists {
+			t.Errorf("Expected failures to be reset after an authenticated request")
+		}
+	})
+}
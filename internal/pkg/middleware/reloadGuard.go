@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+)
+
+// ReloadGuard hands a snapshot of the current options to the request, so
+// that a request never sees a mix of the previous and the reloaded
+// configuration. The configuration is only locked while the snapshot is
+// taken, so that a reload neither waits for long running requests nor
+// holds back the requests arriving in the meantime.
+func ReloadGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(config.Snapshot(r.Context())))
+	})
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+// requestIDHeader is the header used to propagate the correlation ID
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID restricts the correlation IDs accepted from clients, so
+// that they can safely be written to the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID adds a correlation ID to the context of the request, which is
+// included in every log entry written while handling the request. The ID
+// provided by the client in the `X-Request-ID` header is used if present,
+// otherwise a new one is generated. The ID is returned in the
+// `X-Request-ID` header of the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewV4().String()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(log.WithRequestID(r.Context(), requestID)))
+	})
+}
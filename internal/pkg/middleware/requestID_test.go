@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+func TestRequestID(t *testing.T) {
+	var requestID string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = log.RequestID(r.Context())
+	}))
+	testCases := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "the client's request id is propagated", header: "0a1b2c3d-client"},
+		{name: "a request id is generated if missing", header: "", generate: true},
+		{name: "invalid request ids are replaced", header: "bad id\nINFO forged", generate: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Request-ID", tc.header)
+			}
+			handler.ServeHTTP(res, req)
+			if requestID == "" {
+				t.Fatal("Expected the request id to be stored in the context")
+			}
+			if got := res.Header().Get("X-Request-ID"); got != requestID {
+				t.Errorf("Expected response header: '%s', got: '%s'", requestID, got)
+			}
+			if !tc.generate && requestID != tc.header {
+				t.Errorf("Expected: '%s', got: '%s'", tc.header, requestID)
+			}
+			if tc.generate && requestID == tc.header {
+				t.Errorf("Expected a newly generated request id")
+			}
+		})
+	}
+}
@@ -9,8 +9,17 @@ import (
 	"github.com/signavio/workflow-connector/internal/pkg/util"
 )
 
+// writeRoutes are the routes that are rejected for type descriptors backed
+// by a view or an SQL query
+var writeRoutes = map[string]bool{
+	"CreateSingle": true,
+	"UpdateSingle": true,
+	"DeleteSingle": true,
+}
+
 // RouteChecker checks to make sure that the type descriptor key provided
-// by the user actually matches to a database table name
+// by the user actually matches to a database table name, and that read
+// only type descriptors are not written to
 func RouteChecker(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// The value stored in the {table} variable is acutally the "key"
@@ -22,18 +31,27 @@ func RouteChecker(next http.Handler) http.Handler {
 			return
 		}
 		_, ok := util.GetDBTableNameUsingTypeDescriptorKey(
-			config.Options.Descriptor.TypeDescriptors,
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
 			typeDescriptorKey,
 		)
 		if !ok {
-			msg := &util.ResponseMessage{
-				Code: http.StatusNotFound,
-				Msg: fmt.Sprintf(
-					"The requested handler '%s' does not exist",
-					typeDescriptorKey,
-				),
-			}
-			http.Error(w, msg.String(), http.StatusNotFound)
+			msg := util.NewError(util.ErrorCodeNotFound, fmt.Sprintf(
+				"The requested handler '%s' does not exist",
+				typeDescriptorKey,
+			))
+			http.Error(w, msg.String(), msg.Code)
+			return
+		}
+		td := util.GetTypeDescriptorUsingTypeDescriptorKey(
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
+			typeDescriptorKey,
+		)
+		if td.ReadOnly() && writeRoutes[mux.CurrentRoute(r).GetName()] {
+			msg := util.NewError(util.ErrorCodeReadOnly, fmt.Sprintf(
+				"The resources of '%s' are read only",
+				typeDescriptorKey,
+			))
+			http.Error(w, msg.String(), msg.Code)
 			return
 		}
 		next.ServeHTTP(w, r)
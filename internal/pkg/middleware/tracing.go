@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request. If the client sent a
+// W3C `traceparent` header the span continues the trace of the client.
+// The span is named after the matched route.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(
+			r.Context(),
+			propagation.HeaderCarrier(r.Header),
+		)
+		spanName := r.Method
+		if route := mux.CurrentRoute(r); route != nil {
+			spanName = route.GetName()
+		}
+		ctx, span := tracing.Start(
+			ctx,
+			spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", spanName, r)...),
+			trace.WithAttributes(attribute.String("request_id", log.RequestID(r.Context()))),
+		)
+		defer span.End()
+		// Only keys of existing type descriptors are recorded, see Metrics
+		if td := util.GetTypeDescriptorUsingTypeDescriptorKey(
+			config.Current(r.Context()).Descriptor.TypeDescriptors,
+			mux.Vars(r)["table"],
+		); td != nil {
+			span.SetAttributes(attribute.String("type_descriptor", td.Key))
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(rec.status)...)
+		// Client errors are not errors of the server span
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(rec.status))
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	config.Options.Descriptor = &descriptor.Descriptor{
+		TypeDescriptors: []*descriptor.TypeDescriptor{{Key: "equipment"}},
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.HandleFunc("/{table}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	}).Name("GetCollection")
+	router.Use(Tracing)
+	req := httptest.NewRequest("GET", "/equipment", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got: %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GetCollection" {
+		t.Errorf("Expected span to be named after the route, got: %s", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace ID of the traceparent header to be propagated, got: %s", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected span to be a child of the remote span, got parent: %s", got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Expected the span to be available in the context of the handler")
+	}
+}
@@ -0,0 +1,655 @@
+// Package openapi generates an OpenAPI 3 specification of the routes of the
+// workflow connector from the type descriptors of the descriptor
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// Version of the OpenAPI specification the generated documents conform to
+const Version = "3.0.3"
+
+// Document is the root object of an OpenAPI specification
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI schema object used by the generated
+// documents
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// basicAuth is the name of the security scheme of the workflow connector
+const basicAuth = "basicAuth"
+
+// Generate returns the OpenAPI specification of the routes registered by
+// the backend for the type descriptors of cfg.Descriptor
+func Generate(cfg config.Config) *Document {
+	d := cfg.Descriptor
+	if d == nil {
+		d = &descriptor.Descriptor{}
+	}
+	g := &generator{cfg: cfg, descriptor: d}
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       d.Name,
+			Description: d.Description,
+			Version:     strconv.Itoa(d.Version),
+		},
+		Paths: make(map[string]*PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Status": statusSchema(),
+				"Option": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"id":   {Type: "string"},
+						"name": {Type: "string"},
+					},
+					Required: []string{"id", "name"},
+				},
+			},
+			SecuritySchemes: map[string]*SecurityScheme{
+				basicAuth: {
+					Type:        "http",
+					Scheme:      "basic",
+					Description: "The username and password configured in the `auth` option of config.yml",
+				},
+			},
+		},
+		Security: []map[string][]string{{basicAuth: {}}},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = cfg.DisplayName
+	}
+	g.addGeneralPaths(doc)
+	for _, td := range d.TypeDescriptors {
+		g.addTypeDescriptor(doc, td)
+	}
+	return doc
+}
+
+type generator struct {
+	cfg        config.Config
+	descriptor *descriptor.Descriptor
+}
+
+// statusSchema describes the status messages returned for errors and by
+// routes that do not return a resource
+func statusSchema() *Schema {
+	var codes []interface{}
+	for _, code := range []util.ErrorCode{
+		util.ErrorCodeNotFound,
+		util.ErrorCodeValidationFailed,
+		util.ErrorCodeConflict,
+		util.ErrorCodeForbidden,
+		util.ErrorCodeReadOnly,
+		util.ErrorCodeNotImplemented,
+		util.ErrorCodeRateLimited,
+		util.ErrorCodeTimeout,
+		util.ErrorCodeDBUnavailable,
+		util.ErrorCodeInternal,
+	} {
+		codes = append(codes, string(code))
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code":        {Type: "integer"},
+					"description": {Type: "string"},
+					"error": {
+						Type:        "string",
+						Description: "Stable error code, only present in error responses",
+						Enum:        codes,
+					},
+					"tx": {Type: "string", Format: "uuid"},
+				},
+				Required: []string{"code", "description"},
+			},
+		},
+		Required: []string{"status"},
+	}
+}
+
+// responses returns the responses of an operation, consisting of the
+// successful response and the error responses that every route of a type
+// descriptor may return
+func (g *generator) responses(status int, success *Response, errorStatuses ...int) map[string]*Response {
+	responses := map[string]*Response{strconv.Itoa(status): success}
+	errorStatuses = append(errorStatuses,
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	)
+	if g.cfg.IPAllowlist.Enabled {
+		errorStatuses = append(errorStatuses, http.StatusForbidden)
+	}
+	if g.cfg.RateLimit.Enabled {
+		errorStatuses = append(errorStatuses, http.StatusTooManyRequests)
+	}
+	for _, errorStatus := range errorStatuses {
+		response := statusResponse(http.StatusText(errorStatus))
+		if errorStatus == http.StatusTooManyRequests || errorStatus == http.StatusServiceUnavailable {
+			response.Headers = map[string]*Header{
+				"Retry-After": {
+					Description: "Number of seconds to wait before retrying",
+					Schema:      &Schema{Type: "integer"},
+				},
+			}
+		}
+		responses[strconv.Itoa(errorStatus)] = response
+	}
+	return responses
+}
+
+func jsonResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func statusResponse(description string) *Response {
+	return jsonResponse(description, ref("Status"))
+}
+
+func (g *generator) addGeneralPaths(doc *Document) {
+	unauthenticated := []map[string][]string{{}}
+	health := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {Type: "string"},
+			"checks": {
+				Type:                 "object",
+				AdditionalProperties: &Schema{Type: "object"},
+			},
+		},
+	}
+	doc.Paths["/"] = &PathItem{
+		Get: &Operation{
+			OperationID: "GetDescriptorFile",
+			Summary:     "Get the descriptor of the connector",
+			Responses: g.responses(http.StatusOK, jsonResponse(
+				"The merged descriptor",
+				&Schema{Type: "object", Description: "See GET /admin/descriptor-schema"},
+			)),
+		},
+		Post: &Operation{
+			OperationID: "Transaction",
+			Summary:     "Begin or commit a transaction",
+			Description: "Send `?begin` to begin a transaction, whose ID is returned in `status.tx`. " +
+				"Pass the ID as `tx` query parameter to create, update and delete resources within " +
+				"the transaction, and send `?commit={tx}` to commit it. Transactions that are not " +
+				"committed within 60 seconds are rolled back.",
+			Parameters: []*Parameter{
+				{Name: "begin", In: "query", Description: "Begin a new transaction", Schema: &Schema{Type: "string"}},
+				{Name: "commit", In: "query", Description: "ID of the transaction to commit", Schema: &Schema{Type: "string", Format: "uuid"}},
+			},
+			Responses: g.responses(http.StatusOK, statusResponse("The transaction was begun or committed"), http.StatusNotFound),
+		},
+	}
+	doc.Paths["/healthz"] = &PathItem{Get: &Operation{
+		OperationID: "Healthz",
+		Summary:     "Liveness probe",
+		Responses:   map[string]*Response{"200": jsonResponse("The process is alive", health)},
+		Security:    unauthenticated,
+	}}
+	doc.Paths["/readyz"] = &PathItem{Get: &Operation{
+		OperationID: "Readyz",
+		Summary:     "Readiness probe",
+		Responses: map[string]*Response{
+			"200": jsonResponse("The database is reachable", health),
+			"503": jsonResponse("The database is not reachable", health),
+		},
+		Security: unauthenticated,
+	}}
+	doc.Paths["/admin/reload"] = &PathItem{Post: &Operation{
+		OperationID: "ReloadConfig",
+		Summary:     "Reload config.yml and the descriptor",
+		Responses: g.responses(http.StatusOK, statusResponse("The configuration was reloaded"),
+			http.StatusForbidden, http.StatusUnprocessableEntity),
+	}}
+	doc.Paths["/admin/descriptor-schema"] = &PathItem{Get: &Operation{
+		OperationID: "GetDescriptorSchema",
+		Summary:     "Get the JSON Schema of the descriptor",
+		Responses: g.responses(http.StatusOK, &Response{
+			Description: "The JSON Schema of the descriptor",
+			Content:     map[string]*MediaType{"application/schema+json": {Schema: &Schema{Type: "object"}}},
+		}),
+	}}
+	doc.Paths["/openapi.json"] = &PathItem{Get: &Operation{
+		OperationID: "GetOpenAPI",
+		Summary:     "Get this OpenAPI specification",
+		Responses:   g.responses(http.StatusOK, jsonResponse("The OpenAPI specification", &Schema{Type: "object"})),
+	}}
+}
+
+func (g *generator) addTypeDescriptor(doc *Document, td *descriptor.TypeDescriptor) {
+	name := schemaName(td.Key)
+	doc.Components.Schemas[name] = g.resourceSchema(td)
+	doc.Components.Schemas[name+"Input"] = g.inputSchema(td)
+	tags := []string{td.Key}
+	id := &Parameter{Name: "id", In: "path", Required: true, Description: "The unique ID of the resource", Schema: &Schema{Type: "string"}}
+	tx := &Parameter{Name: "tx", In: "query", Description: "ID of the transaction, see POST /", Schema: &Schema{Type: "string", Format: "uuid"}}
+	var denormalize []*Parameter
+	if hasRelationships(td) {
+		denormalize = []*Parameter{{
+			Name:        "$denormalize",
+			In:          "query",
+			Description: "Return the related resources as objects instead of their IDs",
+			Schema:      &Schema{Type: "string"},
+		}}
+	}
+	body := &RequestBody{
+		Required: true,
+		Content: map[string]*MediaType{
+			"application/json":                  {Schema: ref(name + "Input")},
+			"application/x-www-form-urlencoded": {Schema: ref(name + "Input")},
+		},
+	}
+	resource := jsonResponse("The resource", ref(name))
+	rowFilter := ""
+	if td.RowFilter != "" {
+		rowFilter = " Only resources matching the row filter of the authenticated user are returned."
+	}
+
+	doc.Paths["/"+td.Key] = &PathItem{
+		Get: &Operation{
+			OperationID: "GetCollection_" + td.Key,
+			Summary:     fmt.Sprintf("List %s", displayName(td)),
+			Description: "Every query parameter that is a property of " + name + "Input restricts the " +
+				"collection to the resources whose property equals the given value. All matching " +
+				"resources are returned, the collection is not paginated." + rowFilter,
+			Tags:       tags,
+			Parameters: append(g.filterParameters(td), denormalize...),
+			Responses: g.responses(http.StatusOK, jsonResponse(
+				"The matching resources", &Schema{Type: "array", Items: ref(name)},
+			), http.StatusForbidden),
+		},
+		Post: &Operation{
+			OperationID: "CreateSingle_" + td.Key,
+			Summary:     fmt.Sprintf("Create a resource in %s", displayName(td)),
+			Tags:        tags,
+			Parameters:  []*Parameter{tx},
+			RequestBody: body,
+			Responses: g.responses(http.StatusCreated, &Response{
+				Description: "The created resource",
+				Headers: map[string]*Header{
+					"Location": {Description: "URL of the created resource", Schema: &Schema{Type: "string"}},
+				},
+				Content: resource.Content,
+			}, http.StatusConflict),
+		},
+	}
+	doc.Paths["/"+td.Key+"/{id}"] = &PathItem{
+		Get: &Operation{
+			OperationID: "GetSingle_" + td.Key,
+			Summary:     fmt.Sprintf("Get a resource of %s", displayName(td)),
+			Tags:        tags,
+			Parameters:  append([]*Parameter{id}, denormalize...),
+			Responses:   g.responses(http.StatusOK, resource, http.StatusNotFound, http.StatusForbidden),
+		},
+		Patch: &Operation{
+			OperationID: "UpdateSingle_" + td.Key,
+			Summary:     fmt.Sprintf("Update a resource of %s", displayName(td)),
+			Description: "Only the properties contained in the request body are updated.",
+			Tags:        tags,
+			Parameters:  []*Parameter{id, tx},
+			RequestBody: body,
+			Responses:   g.responses(http.StatusOK, jsonResponse("The updated resource", ref(name)), http.StatusNotFound, http.StatusForbidden, http.StatusConflict),
+		},
+		Delete: &Operation{
+			OperationID: "DeleteSingle_" + td.Key,
+			Summary:     fmt.Sprintf("Delete a resource of %s", displayName(td)),
+			Tags:        tags,
+			Parameters:  []*Parameter{id, tx},
+			Responses:   g.responses(http.StatusOK, statusResponse("The resource was deleted"), http.StatusNotFound, http.StatusForbidden, http.StatusConflict),
+		},
+	}
+	if td.ReadOnly() {
+		// Type descriptors backed by a view or a query are read only
+		doc.Paths["/"+td.Key].Post = nil
+		doc.Paths["/"+td.Key+"/{id}"].Patch = nil
+		doc.Paths["/"+td.Key+"/{id}"].Delete = nil
+	}
+	doc.Paths["/"+td.Key+"/options"] = &PathItem{Get: &Operation{
+		OperationID: "GetCollectionAsOptions_" + td.Key,
+		Summary:     fmt.Sprintf("List %s as options", displayName(td)),
+		Description: "At most 42 options are returned. Query parameters that are properties of " +
+			name + "Input restrict the options like they restrict the collection.",
+		Tags: tags,
+		Parameters: append([]*Parameter{{
+			Name:        "filter",
+			In:          "query",
+			Description: "Only return options whose name contains this value",
+			Schema:      &Schema{Type: "string"},
+		}}, g.filterParameters(td)...),
+		Responses: g.responses(http.StatusOK, jsonResponse(
+			"The matching options", &Schema{Type: "array", Items: ref("Option")},
+		), http.StatusForbidden),
+	}}
+	doc.Paths["/"+td.Key+"/options/{id}"] = &PathItem{Get: &Operation{
+		OperationID: "GetSingleAsOption_" + td.Key,
+		Summary:     fmt.Sprintf("Get a resource of %s as option", displayName(td)),
+		Tags:        tags,
+		Parameters:  []*Parameter{id},
+		Responses:   g.responses(http.StatusOK, jsonResponse("The option", ref("Option")), http.StatusNotFound, http.StatusForbidden),
+	}}
+	g.addActions(doc, td, tags, id)
+}
+
+// addActions specifies the route calling the actions of a type descriptor.
+// The route exists for every type descriptor, and answers with 404 Not
+// Found if the type descriptor has no action of the given name.
+func (g *generator) addActions(doc *Document, td *descriptor.TypeDescriptor, tags []string, id *Parameter) {
+	name := &Parameter{Name: "name", In: "path", Required: true, Description: "The name of the action", Schema: &Schema{Type: "string"}}
+	var inputs, outputs []*Schema
+	for _, action := range td.Actions {
+		name.Schema.Enum = append(name.Schema.Enum, action.Name)
+		actionName := schemaName(td.Key) + schemaName(action.Name)
+		doc.Components.Schemas[actionName+"Input"] = parametersSchema(action.Parameters)
+		output := parametersSchema(action.Output)
+		if action.ResultSet {
+			output = &Schema{Type: "array", Items: output}
+		}
+		doc.Components.Schemas[actionName+"Output"] = output
+		inputs = append(inputs, ref(actionName+"Input"))
+		outputs = append(outputs, ref(actionName+"Output"))
+	}
+	operation := &Operation{
+		OperationID: "ExecAction_" + td.Key,
+		Summary:     fmt.Sprintf("Carry out an action on a resource of %s", displayName(td)),
+		Description: "Calls the stored procedure of the action with the ID of the resource and the " +
+			"parameters in the request body. The response contains the output parameters of the " +
+			"procedure, or the rows of its result set as an array.",
+		Tags:       tags,
+		Parameters: []*Parameter{id, name},
+		Responses: g.responses(http.StatusOK, jsonResponse("The output of the action", &Schema{OneOf: outputs}),
+			http.StatusNotFound, http.StatusForbidden, http.StatusConflict, http.StatusNotImplemented),
+	}
+	if len(inputs) > 0 {
+		operation.RequestBody = &RequestBody{
+			Content: map[string]*MediaType{
+				"application/json":                  {Schema: &Schema{OneOf: inputs}},
+				"application/x-www-form-urlencoded": {Schema: &Schema{OneOf: inputs}},
+			},
+		}
+	}
+	doc.Paths["/"+td.Key+"/{id}/actions/{name}"] = &PathItem{Post: operation}
+}
+
+// parametersSchema describes the input or output parameters of an action
+func parametersSchema(parameters []*descriptor.Parameter) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, parameter := range parameters {
+		s := valueSchema(&descriptor.Field{Key: parameter.Key, Type: parameter.Type})
+		s.Nullable = true
+		schema.Properties[parameter.Key] = s
+	}
+	return schema
+}
+
+// resourceSchema describes a resource as it is formatted by the
+// formatting package. The unique ID and the option name are always
+// returned as `id` and `name`.
+func (g *generator) resourceSchema(td *descriptor.TypeDescriptor) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"id": {Type: "string"}},
+		Required:   []string{"id"},
+	}
+	for _, field := range td.Fields {
+		key := field.Key
+		switch {
+		case field.Relationship != nil:
+			schema.Properties[key] = g.relationshipSchema(field.Relationship)
+			continue
+		case field.FromColumn != "" && field.FromColumn == td.UniqueIdColumn:
+			continue
+		case field.FromColumn != "" && field.FromColumn == td.ColumnAsOptionName:
+			key = "name"
+		}
+		s := valueSchema(field)
+		s.Nullable = true
+		s.ReadOnly = len(field.Expression) > 0
+		schema.Properties[key] = s
+	}
+	return schema
+}
+
+// relationshipSchema describes the related resources, which are returned
+// as IDs or, if `$denormalize` is given, as objects
+func (g *generator) relationshipSchema(r *descriptor.Relationship) *Schema {
+	related := &Schema{Type: "object", Description: "The related resource, if `$denormalize` is given"}
+	if td := util.GetTypeDescriptorUsingDBTableName(g.descriptor.TypeDescriptors, r.WithTable); td != nil {
+		related = ref(schemaName(td.Key))
+	}
+	one := &Schema{OneOf: []*Schema{{Type: "string"}, related}}
+	if r.Kind == "oneToMany" {
+		return &Schema{Type: "array", Items: one}
+	}
+	empty := 0
+	one.OneOf = append(one.OneOf, &Schema{
+		Type:        "array",
+		MaxItems:    &empty,
+		Description: "An empty array is returned if there is no related resource",
+	})
+	return one
+}
+
+// inputSchema describes the request data accepted when creating or
+// updating a resource. Computed fields are read only and are missing.
+func (g *generator) inputSchema(td *descriptor.TypeDescriptor) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range td.Fields {
+		if field.Relationship != nil || field.Type == nil || len(field.Expression) > 0 {
+			continue
+		}
+		if field.Type.Name == "money" {
+			if amount := field.Type.Amount; amount != nil && amount.Key != "" {
+				schema.Properties[amount.Key] = &Schema{Type: "number"}
+			}
+			if currency := field.Type.Currency; currency != nil && currency.Key != "" {
+				schema.Properties[currency.Key] = &Schema{Type: "string"}
+			}
+			continue
+		}
+		schema.Properties[field.Key] = valueSchema(field)
+	}
+	return schema
+}
+
+func (g *generator) filterParameters(td *descriptor.TypeDescriptor) (parameters []*Parameter) {
+	input := g.inputSchema(td)
+	for _, field := range td.Fields {
+		if field.Relationship != nil || field.Type == nil {
+			continue
+		}
+		keys := []string{field.Key}
+		if field.Type.Name == "money" {
+			keys = nil
+			if field.Type.Amount != nil && field.Type.Amount.Key != "" {
+				keys = append(keys, field.Type.Amount.Key)
+			}
+			if field.Type.Currency != nil && field.Type.Currency.Key != "" {
+				keys = append(keys, field.Type.Currency.Key)
+			}
+		}
+		for _, key := range keys {
+			schema, ok := input.Properties[key]
+			if !ok {
+				// Computed fields can be filtered on as well
+				schema = valueSchema(field)
+			}
+			parameters = append(parameters, &Parameter{
+				Name:   key,
+				In:     "query",
+				Schema: schema,
+			})
+		}
+	}
+	return parameters
+}
+
+// valueSchema describes the value of a field that is not a relationship
+func valueSchema(field *descriptor.Field) *Schema {
+	if field.Type == nil {
+		return &Schema{}
+	}
+	switch field.Type.Name {
+	case "text", "user":
+		return &Schema{Type: "string"}
+	case "email":
+		return &Schema{Type: "string", Format: "email"}
+	case "link":
+		return &Schema{Type: "string", Format: "uri"}
+	case "number":
+		return &Schema{Type: "number"}
+	case "boolean":
+		return &Schema{Type: "boolean"}
+	case "date":
+		// Dates and times are formatted as date times as well
+		return &Schema{Type: "string", Format: "date-time"}
+	case "money":
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"amount":   {Type: "number"},
+				"currency": {Type: "string"},
+			},
+		}
+	case "choice":
+		s := &Schema{Type: "string"}
+		for _, option := range field.Type.Options {
+			s.Enum = append(s.Enum, option.Id)
+		}
+		return s
+	case "list":
+		items := &Schema{Type: "string"}
+		if field.Type.ElementType != nil {
+			items = valueSchema(&descriptor.Field{Type: &descriptor.WorkflowType{Name: field.Type.ElementType.Name}})
+		}
+		return &Schema{Type: "array", Items: items}
+	default:
+		return &Schema{}
+	}
+}
+
+func hasRelationships(td *descriptor.TypeDescriptor) bool {
+	for _, field := range td.Fields {
+		if field.Relationship != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName returns the name of the component schema of a type
+// descriptor, for example `Equipment` for the key `equipment`
+func schemaName(key string) string {
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+func displayName(td *descriptor.TypeDescriptor) string {
+	if td.Name != "" {
+		return td.Name
+	}
+	return td.Key
+}
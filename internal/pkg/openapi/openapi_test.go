@@ -0,0 +1,76 @@
+package openapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/signavio/workflow-connector/internal/app/backend"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/openapi"
+)
+
+// TestGenerateCoversAllRoutes makes sure that the specification stays in
+// sync with the routes registered by the backend
+func TestGenerateCoversAllRoutes(t *testing.T) {
+	doc := openapi.Generate(config.Options)
+	router := (&backend.Backend{}).GetHandler().(*mux.Router)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, td := range config.Options.Descriptor.TypeDescriptors {
+			path := strings.Replace(template, "{table}", td.Key, 1)
+			item, ok := doc.Paths[path]
+			if !ok {
+				t.Errorf("Expected path %s of route %s to be specified", path, route.GetName())
+				continue
+			}
+			for _, method := range methods {
+				operations := map[string]*openapi.Operation{
+					"GET": item.Get, "POST": item.Post, "PATCH": item.Patch, "DELETE": item.Delete,
+				}
+				if operations[method] == nil {
+					t.Errorf("Expected operation %s %s of route %s to be specified", method, path, route.GetName())
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateSchemas(t *testing.T) {
+	doc := openapi.Generate(config.Options)
+	equipment := doc.Components.Schemas["Equipment"]
+	if equipment == nil {
+		t.Fatalf("Expected a schema for the equipment type descriptor")
+	}
+	expected := map[string]string{
+		"id":              "string",
+		"name":            "string",
+		"acquisitionCost": "object",
+		"purchaseDate":    "string",
+		"recipes":         "array",
+	}
+	for property, typ := range expected {
+		schema, ok := equipment.Properties[property]
+		if !ok || schema.Type != typ {
+			t.Errorf("Expected property %s of type %s, got: %+v", property, typ, schema)
+		}
+	}
+	if equipment.Properties["purchaseDate"].Format != "date-time" {
+		t.Errorf("Expected dates to be formatted as date-time")
+	}
+	input := doc.Components.Schemas["EquipmentInput"]
+	if _, ok := input.Properties["acquisitionCost"]; !ok {
+		t.Errorf("Expected the amount of money fields to be accepted as input, got: %+v", input.Properties)
+	}
+}
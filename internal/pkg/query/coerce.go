@@ -0,0 +1,182 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+// CoerceValue converts a value received in the request data to the golang
+// native type that is written to the database for the given workflow type.
+// Request data is either decoded from JSON or, for form encoded requests
+// and query parameters, received as strings, so both are accepted. Values
+// of a type that is not handled here are returned unchanged.
+func CoerceValue(workflowType *descriptor.WorkflowType, value interface{}) (interface{}, error) {
+	if value == nil || workflowType == nil {
+		return value, nil
+	}
+	switch workflowType.Name {
+	case "boolean":
+		return coerceBoolean(value)
+	case "number":
+		return coerceNumber(value)
+	case "text", "user":
+		return coerceText(value)
+	case "email":
+		return coerceEmail(value)
+	case "link":
+		return coerceLink(value)
+	case "choice":
+		return coerceChoice(workflowType.Options, value)
+	case "list":
+		return coerceList(workflowType.ElementType, value)
+	default:
+		return value, nil
+	}
+}
+
+func coerceBoolean(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a boolean, got %s", describe(value))
+}
+
+func coerceNumber(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f, nil
+		}
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a number, got %s", describe(value))
+}
+
+func coerceText(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return nil, fmt.Errorf("expected a string, got %s", describe(value))
+}
+
+func coerceEmail(value interface{}) (interface{}, error) {
+	v, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected an email address, got %s", describe(value))
+	}
+	if v == "" {
+		return v, nil
+	}
+	// Only accept plain addresses, like `jane@example.com`, and not
+	// addresses with a display name, like `Jane <jane@example.com>`
+	if address, err := mail.ParseAddress(v); err != nil || address.Address != v {
+		return nil, fmt.Errorf("expected an email address, got %s", describe(value))
+	}
+	return v, nil
+}
+
+func coerceLink(value interface{}) (interface{}, error) {
+	v, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a link, got %s", describe(value))
+	}
+	if v == "" {
+		return v, nil
+	}
+	if u, err := url.Parse(v); err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("expected an absolute URL, got %s", describe(value))
+	}
+	return v, nil
+}
+
+func coerceChoice(options []*descriptor.Option, value interface{}) (interface{}, error) {
+	v, err := coerceText(value)
+	if err != nil || len(options) == 0 {
+		return v, err
+	}
+	var ids []string
+	for _, option := range options {
+		if option.Id == v {
+			return v, nil
+		}
+		ids = append(ids, strconv.Quote(option.Id))
+	}
+	return nil, fmt.Errorf(
+		"expected one of the options %s, got %s",
+		strings.Join(ids, ", "), describe(value),
+	)
+}
+
+// coerceList converts each element of a list to the element type of the
+// list. Lists are written to the database as a JSON array.
+func coerceList(elementType *descriptor.ElementType, value interface{}) (interface{}, error) {
+	var elements []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		elements = v
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(v), &elements); err != nil {
+			return nil, fmt.Errorf("expected a list, got %s", describe(value))
+		}
+	default:
+		return nil, fmt.Errorf("expected a list, got %s", describe(value))
+	}
+	element := &descriptor.WorkflowType{Name: "text"}
+	if elementType != nil {
+		element = &descriptor.WorkflowType{Name: elementType.Name}
+	}
+	coerced := make([]interface{}, 0, len(elements))
+	for i, e := range elements {
+		c, err := CoerceValue(element, e)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		coerced = append(coerced, c)
+	}
+	list, err := json.Marshal(coerced)
+	if err != nil {
+		return nil, err
+	}
+	return string(list), nil
+}
+
+func describe(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -3,11 +3,13 @@ package query
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"text/template"
 
 	"github.com/signavio/workflow-connector/internal/pkg/config"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
 )
 
@@ -17,13 +19,35 @@ type QueryTemplate struct {
 	ColumnNames      []string
 	CoerceArgFuncs   map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error)
 	QueryFormatFuncs map[string]func() string
+	// TableSource returns what the `source` function of the template
+	// selects from in place of a table, see SqlBackend.TableSource
+	TableSource func(context.Context, string) (string, error)
 }
 
 func (e *QueryTemplate) Interpolate(ctx context.Context, requestData map[string]interface{}) (interpolatedQuery string, args []interface{}, err error) {
+	_, span := tracing.Start(ctx, "query.Interpolate")
+	defer func() { tracing.End(span, err) }()
 	templateText := e.Vars[0]
 	currentTable := ctx.Value(util.ContextKey("table")).(string)
 
 	funcMap := template.FuncMap{
+		"source": func(tableName string) (string, error) {
+			if e.TableSource == nil {
+				return "", fmt.Errorf("query template for table '%s' has no table source", tableName)
+			}
+			return e.TableSource(ctx, tableName)
+		},
+		// rowFilter returns the row filter of the type descriptor of a
+		// related table, which restricts the related rows that are joined.
+		// Its `{{principal.attributeName}}` placeholders are left in the
+		// query and bound by the backend, see InterpolateQueryParameters.
+		"rowFilter": func(tableName string) string {
+			td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, tableName)
+			if td == nil {
+				return ""
+			}
+			return td.RowFilter
+		},
 		"add2": func(x int) int {
 			return x + 2
 		},
@@ -40,7 +64,7 @@ func (e *QueryTemplate) Interpolate(ctx context.Context, requestData map[string]
 			return func(idx int, columnName string) string {
 
 				nextIdx := fmt.Sprintf(":%d", idx+2)
-				td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, tableName)
+				td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, tableName)
 				for _, field := range td.Fields {
 					switch field.Type.Name {
 					case "money":
@@ -83,76 +107,54 @@ func (e *QueryTemplate) Interpolate(ctx context.Context, requestData map[string]
 	}
 	args, err = CoerceRequestDataToGolangNativeTypes(ctx, requestData, e.CoerceArgFuncs)
 	if err != nil {
-		return "", nil, err
+		return "", nil, ErrInvalidRequestData{err}
 	}
 	return query.String(), args, nil
 
 }
 func CoerceRequestDataToGolangNativeTypes(ctx context.Context, requestData map[string]interface{}, coerceArgFuncs map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error)) (args []interface{}, err error) {
 	currentTable := ctx.Value(util.ContextKey("table")).(string)
-	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, currentTable)
+	td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, currentTable)
 	for _, field := range td.Fields {
-		switch field.Type.Name {
-		case "money":
-			result, ok, err := coerceArgFuncs["money"](requestData, field)
-			if err != nil {
-				return args, err
-			}
-			if ok {
-				args = append(args, result)
-			}
-		// FIXME: is datetime here necessary?
-		case "datetime":
-			result, ok, err := coerceArgFuncs["datetime"](requestData, field)
-			if err != nil {
-				return args, err
-			}
-			if ok {
-				args = append(args, result)
-			}
-		case "date":
-			switch field.Type.Kind {
-			case "date":
-				result, ok, err := coerceArgFuncs["date"](requestData, field)
-				if err != nil {
-					return args, err
-				}
-				if ok {
-					args = append(args, result)
-				}
-			case "datetime":
-				result, ok, err := coerceArgFuncs["datetime"](requestData, field)
-				if err != nil {
-					return args, err
-				}
-				if ok {
-					args = append(args, result)
-				}
-			case "time":
-				result, ok, err := coerceArgFuncs["time"](requestData, field)
-				if err != nil {
-					return args, err
-				}
-				if ok {
-					args = append(args, result)
-				}
-			}
-		default:
-			result, ok, err := coerceArgFuncs["default"](requestData, field)
-			if err != nil {
-				return args, err
-			}
-			if ok {
-				args = append(args, result)
-			}
+		result, ok, err := CoerceArg(requestData, field, coerceArgFuncs)
+		if err != nil {
+			return args, err
+		}
+		if ok {
+			args = append(args, result)
 		}
 	}
 	return
 }
 
-func format(idx int, columnName string, tableName string, queryFormatFuncs map[string]func() string) string {
+// CoerceArg converts the value of field in the request data to the golang
+// type passed to the database. It reports false if the request data does
+// not contain a value for the field.
+func CoerceArg(requestData map[string]interface{}, field *descriptor.Field, coerceArgFuncs map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error)) (interface{}, bool, error) {
+	switch field.Type.Name {
+	// FIXME: is datetime here necessary?
+	case "money", "datetime":
+		return coerceArgFuncs[field.Type.Name](requestData, field)
+	case "date":
+		switch field.Type.Kind {
+		case "date", "datetime", "time":
+			return coerceArgFuncs[field.Type.Kind](requestData, field)
+		}
+		return nil, false, nil
+	default:
+		// Relationships are passed through, all other workflow
+		// types may register a function to coerce their values
+		coerceArgFunc, ok := coerceArgFuncs[field.Type.Name]
+		if !ok || field.Relationship != nil {
+			coerceArgFunc = coerceArgFuncs["default"]
+		}
+		return coerceArgFunc(requestData, field)
+	}
+}
+
+func format(ctx context.Context, idx int, columnName string, tableName string, queryFormatFuncs map[string]func() string) string {
 	nextIdx := fmt.Sprintf(":%d", idx+2)
-	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, tableName)
+	td := util.GetTypeDescriptorUsingDBTableName(config.Current(ctx).Descriptor.TypeDescriptors, tableName)
 	for _, field := range td.Fields {
 		switch field.Type.Name {
 		case "money":
@@ -182,3 +184,141 @@ func format(idx int, columnName string, tableName string, queryFormatFuncs map[s
 	}
 	return nextIdx
 }
+
+// InterpolateRowFilter replaces every `{{principal.attributeName}}`
+// placeholder in the row filter with a bind parameter, and returns the
+// values of the principal's attributes as the arguments for these bind
+// parameters. The first bind parameter will be at the given position,
+// formatted by the placeholder function of the current backend.
+func InterpolateRowFilter(rowFilter string, principal *util.Principal, placeholder func(int) string, position int) (filter string, args []interface{}, err error) {
+	if rowFilter == "" {
+		return "", nil, nil
+	}
+	filter = descriptor.RowFilterPlaceholder.ReplaceAllStringFunc(rowFilter, func(match string) string {
+		if err != nil {
+			return match
+		}
+		name := descriptor.RowFilterPlaceholder.FindStringSubmatch(match)[1]
+		value, ok := principal.Attribute(name)
+		if !ok {
+			err = ErrMissingPrincipalAttribute{name}
+			return match
+		}
+		args = append(args, value)
+		return placeholder(position + len(args) - 1)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return filter, args, nil
+}
+
+// InterpolateQueryParameters replaces every `{{principal.attributeName}}`
+// placeholder, which the SQL query of a type descriptor leaves in the
+// interpolated query q, with a bind parameter. The values of the
+// parameters, as returned by value, are inserted into args so that they
+// are bound to their placeholders: after all other arguments if the
+// backend numbers its placeholders, otherwise in the order in which the
+// placeholders appear in q. Question marks within quoted strings are not
+// counted as placeholders.
+func InterpolateQueryParameters(q string, args []interface{}, value func(name string) (interface{}, error), placeholder func(int) string) (string, []interface{}, error) {
+	matches := descriptor.RowFilterPlaceholder.FindAllStringSubmatchIndex(q, -1)
+	if len(matches) == 0 {
+		return q, args, nil
+	}
+	positional := placeholder(1) == placeholder(2)
+	interpolated := bytes.NewBufferString("")
+	var bound []interface{}
+	next, end := 0, 0
+	for _, match := range matches {
+		v, err := value(q[match[2]:match[3]])
+		if err != nil {
+			return "", nil, err
+		}
+		preceding := q[end:match[0]]
+		interpolated.WriteString(preceding)
+		if positional {
+			n := next + countPositionalPlaceholders(preceding)
+			if n > len(args) {
+				n = len(args)
+			}
+			bound = append(append(bound, args[next:n]...), v)
+			next = n
+			interpolated.WriteString(placeholder(len(bound)))
+		} else {
+			bound = append(bound, v)
+			interpolated.WriteString(placeholder(len(args) + len(bound)))
+		}
+		end = match[1]
+	}
+	interpolated.WriteString(q[end:])
+	if positional {
+		return interpolated.String(), append(bound, args[next:]...), nil
+	}
+	return interpolated.String(), append(args[:len(args):len(args)], bound...), nil
+}
+
+// PrincipalAttributes returns the values of the principal's attributes to
+// InterpolateQueryParameters
+func PrincipalAttributes(principal *util.Principal) func(string) (interface{}, error) {
+	return func(name string) (interface{}, error) {
+		value, ok := principal.Attribute(name)
+		if !ok {
+			return nil, ErrMissingPrincipalAttribute{name}
+		}
+		return value, nil
+	}
+}
+
+// countPositionalPlaceholders counts the `?` placeholders in s which are
+// not part of a quoted string or identifier
+func countPositionalPlaceholders(s string) (count int) {
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			count++
+		}
+	}
+	return
+}
+
+// ErrRowFilterViolated is returned when a created or updated row does not
+// match the row filter of its type descriptor
+var ErrRowFilterViolated = errors.New(
+	"the resource does not match the row filter of the type descriptor",
+)
+
+// ErrMissingPrincipalAttribute is returned when the row filter of a type
+// descriptor references an attribute the current principal does not have
+type ErrMissingPrincipalAttribute struct {
+	Name string
+}
+
+func (e ErrMissingPrincipalAttribute) Error() string {
+	return fmt.Sprintf(
+		"the authenticated user is missing the attribute '%s' "+
+			"required to access this resource",
+		e.Name,
+	)
+}
+
+// ErrInvalidRequestData is returned when a value in the request data can
+// not be converted to the type of its field in the type descriptor
+type ErrInvalidRequestData struct {
+	Err error
+}
+
+func (e ErrInvalidRequestData) Error() string {
+	return fmt.Sprintf("the request data contains an invalid value: %s", e.Err)
+}
+
+func (e ErrInvalidRequestData) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,114 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+func TestInterpolateRowFilter(t *testing.T) {
+	principal := &util.Principal{
+		Username: "wfauser",
+		Attributes: map[string]string{
+			// the config file parser lowercases map keys
+			"costcenter": "4711",
+			"region":     "EMEA",
+		},
+	}
+	dollar := func(position int) string { return fmt.Sprintf("$%d", position) }
+	t.Run("success cases", func(t *testing.T) {
+		filter, args, err := InterpolateRowFilter(
+			`"cost_center" = {{principal.costCenter}} OR ("region" = {{ principal.region }} AND "owner" = {{principal.username}})`,
+			principal,
+			dollar,
+			3,
+		)
+		if err != nil {
+			t.Errorf("Expected no error, instead got: '%v'", err)
+		}
+		want := `"cost_center" = $3 OR ("region" = $4 AND "owner" = $5)`
+		if filter != want {
+			t.Errorf("Expected: '%s', got: '%s'", want, filter)
+		}
+		wantArgs := []interface{}{"4711", "EMEA", "wfauser"}
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("Expected: '%v', got: '%v'", wantArgs, args)
+		}
+		filter, args, err = InterpolateRowFilter("", principal, dollar, 1)
+		if err != nil || filter != "" || args != nil {
+			t.Errorf("Expected an empty row filter to be a no-op")
+		}
+	})
+	t.Run("failure cases", func(t *testing.T) {
+		_, _, err := InterpolateRowFilter(`"plant" = {{principal.plant}}`, principal, dollar, 1)
+		if _, ok := err.(ErrMissingPrincipalAttribute); !ok {
+			t.Errorf("Expected ErrMissingPrincipalAttribute, instead got: '%v'", err)
+		}
+		_, _, err = InterpolateRowFilter(`"cost_center" = {{principal.costCenter}}`, nil, dollar, 1)
+		if err == nil {
+			t.Errorf("Expected an error when no principal is authenticated")
+		}
+	})
+}
+
+func TestInterpolateQueryParameters(t *testing.T) {
+	principal := &util.Principal{
+		Username:   "wfauser",
+		Attributes: map[string]string{"region": "EMEA"},
+	}
+	dollar := func(position int) string { return fmt.Sprintf("$%d", position) }
+	question := func(int) string { return "?" }
+	t.Run("success cases", func(t *testing.T) {
+		q, args, err := InterpolateQueryParameters(
+			`SELECT * FROM (SELECT * FROM equipment WHERE region = {{principal.region}}) AS "equipment" WHERE "id" = $1 AND "owner" = $2`,
+			[]interface{}{"1", "wfauser"},
+			PrincipalAttributes(principal),
+			dollar,
+		)
+		if err != nil {
+			t.Errorf("Expected no error, instead got: '%v'", err)
+		}
+		want := `SELECT * FROM (SELECT * FROM equipment WHERE region = $3) AS "equipment" WHERE "id" = $1 AND "owner" = $2`
+		if q != want {
+			t.Errorf("Expected: '%s', got: '%s'", want, q)
+		}
+		wantArgs := []interface{}{"1", "wfauser", "EMEA"}
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("Expected: '%v', got: '%v'", wantArgs, args)
+		}
+		q, args, err = InterpolateQueryParameters(
+			"SELECT * FROM (SELECT * FROM equipment WHERE name <> '?' AND region = {{principal.region}}) AS `equipment` WHERE `id` = ? AND `id` IN (SELECT `id` FROM (SELECT * FROM equipment WHERE region = {{principal.region}}) AS `equipment` WHERE `owner` = ?)",
+			[]interface{}{"1", "wfauser"},
+			PrincipalAttributes(principal),
+			question,
+		)
+		if err != nil {
+			t.Errorf("Expected no error, instead got: '%v'", err)
+		}
+		want = "SELECT * FROM (SELECT * FROM equipment WHERE name <> '?' AND region = ?) AS `equipment` WHERE `id` = ? AND `id` IN (SELECT `id` FROM (SELECT * FROM equipment WHERE region = ?) AS `equipment` WHERE `owner` = ?)"
+		if q != want {
+			t.Errorf("Expected: '%s', got: '%s'", want, q)
+		}
+		wantArgs = []interface{}{"EMEA", "1", "EMEA", "wfauser"}
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("Expected: '%v', got: '%v'", wantArgs, args)
+		}
+		q, args, err = InterpolateQueryParameters(`SELECT * FROM equipment WHERE "id" = $1`, []interface{}{"1"}, PrincipalAttributes(principal), dollar)
+		if err != nil || q != `SELECT * FROM equipment WHERE "id" = $1` || !reflect.DeepEqual(args, []interface{}{"1"}) {
+			t.Errorf("Expected a query without parameters to be a no-op")
+		}
+	})
+	t.Run("failure cases", func(t *testing.T) {
+		_, _, err := InterpolateQueryParameters(
+			`SELECT * FROM equipment WHERE plant = {{principal.plant}}`,
+			nil,
+			PrincipalAttributes(principal),
+			dollar,
+		)
+		if _, ok := err.(ErrMissingPrincipalAttribute); !ok {
+			t.Errorf("Expected ErrMissingPrincipalAttribute, instead got: '%v'", err)
+		}
+	})
+}
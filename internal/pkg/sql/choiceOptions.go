@@ -0,0 +1,190 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+)
+
+// choiceOptionsIdleInterval is the time after which the refresh loop
+// checks again whether a reloaded configuration enabled the refresh
+const choiceOptionsIdleInterval = time.Minute
+
+// choiceOptionsLookups returns the workflow types of all choice fields
+// in the descriptor which load their options from a lookup table
+func choiceOptionsLookups(d *descriptor.Descriptor) (lookups []*descriptor.WorkflowType) {
+	if d == nil {
+		return nil
+	}
+	for _, td := range d.TypeDescriptors {
+		for _, field := range td.Fields {
+			if field.Type != nil && field.Type.OptionsFromTable != nil {
+				lookups = append(lookups, field.Type)
+			}
+		}
+	}
+	return lookups
+}
+
+// LoadChoiceOptions replaces the options of the choice fields which
+// reference a lookup table with the rows currently in the table. It
+// changes the current descriptor in place, and is therefore only called
+// while no requests are handled, that is when the database is opened.
+func (s *SqlBackend) LoadChoiceOptions(ctx context.Context) error {
+	return s.loadChoiceOptions(ctx, config.Options.Descriptor)
+}
+
+// loadChoiceOptions replaces the options of the choice fields of the
+// descriptor d, which is either the current descriptor or a reloaded
+// descriptor that is not in use yet
+func (s *SqlBackend) loadChoiceOptions(ctx context.Context, d *descriptor.Descriptor) error {
+	options, err := s.queryChoiceOptions(ctx, choiceOptionsLookups(d))
+	if err != nil {
+		return err
+	}
+	for workflowType, o := range options {
+		workflowType.Options = o
+	}
+	return nil
+}
+
+// RefreshChoiceOptions starts refreshing the options of the choice fields
+// in the background, until the backend is closed
+func (s *SqlBackend) RefreshChoiceOptions() {
+	s.stopRefresh = make(chan struct{})
+	go s.refreshChoiceOptions(s.stopRefresh)
+}
+
+// refreshChoiceOptions reloads the options of the choice fields every
+// `choiceOptions.refreshInterval` until stop is closed. The current
+// options are kept if the lookup tables can not be queried.
+func (s *SqlBackend) refreshChoiceOptions(stop <-chan struct{}) {
+	for {
+		config.ReloadLock.RLock()
+		interval := config.Options.ChoiceOptions.RefreshInterval
+		config.ReloadLock.RUnlock()
+		if interval <= 0 {
+			interval = choiceOptionsIdleInterval
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		config.ReloadLock.RLock()
+		enabled := config.Options.ChoiceOptions.RefreshInterval > 0
+		current := config.Options.Descriptor
+		config.ReloadLock.RUnlock()
+		lookups := choiceOptionsLookups(current)
+		if !enabled || len(lookups) == 0 {
+			continue
+		}
+		options, err := s.queryChoiceOptions(context.Background(), lookups)
+		if err != nil {
+			log.When(true).Warnf(
+				"[backend] unable to refresh choice options, keeping the current options: %s\n", err,
+			)
+			continue
+		}
+		// Requests may still read the current descriptor, so a copy of it
+		// holding the refreshed options is swapped in. If the configuration
+		// was reloaded in the meantime, the refreshed options are dropped,
+		// since the reloaded descriptor loaded its own options.
+		refreshed := withChoiceOptions(current, options)
+		config.ReloadLock.Lock()
+		swapped := config.Options.Descriptor == current
+		if swapped {
+			config.Options.Descriptor = refreshed
+		}
+		config.ReloadLock.Unlock()
+		if swapped {
+			log.When(config.Logging()).Debugf(
+				"[backend] refreshed the options of %d choice field(s)\n", len(options),
+			)
+		}
+	}
+}
+
+// withChoiceOptions returns a copy of the descriptor d in which the
+// workflow types in options have the given options. Only the type
+// descriptors, fields and workflow types on the way to the changed
+// options are copied, d itself is left unchanged.
+func withChoiceOptions(d *descriptor.Descriptor, options map[*descriptor.WorkflowType][]*descriptor.Option) *descriptor.Descriptor {
+	copied := *d
+	copied.TypeDescriptors = make([]*descriptor.TypeDescriptor, len(d.TypeDescriptors))
+	for i, td := range d.TypeDescriptors {
+		copiedTd := *td
+		copiedTd.Fields = make([]*descriptor.Field, len(td.Fields))
+		for j, field := range td.Fields {
+			o, ok := options[field.Type]
+			if !ok {
+				copiedTd.Fields[j] = field
+				continue
+			}
+			copiedField := *field
+			copiedType := *field.Type
+			copiedType.Options = o
+			copiedField.Type = &copiedType
+			copiedTd.Fields[j] = &copiedField
+		}
+		copied.TypeDescriptors[i] = &copiedTd
+	}
+	return &copied
+}
+
+func (s *SqlBackend) queryChoiceOptions(ctx context.Context, lookups []*descriptor.WorkflowType) (map[*descriptor.WorkflowType][]*descriptor.Option, error) {
+	options := make(map[*descriptor.WorkflowType][]*descriptor.Option)
+	if len(lookups) == 0 {
+		return options, nil
+	}
+	queryTemplate, err := template.New("GetChoiceOptions").Parse(s.getQueryTemplate("GetChoiceOptions"))
+	if err != nil {
+		return nil, err
+	}
+	for _, workflowType := range lookups {
+		lookup := workflowType.OptionsFromTable
+		query := bytes.NewBufferString("")
+		if err := queryTemplate.Execute(query, lookup); err != nil {
+			return nil, err
+		}
+		o, err := s.queryOptions(ctx, query.String())
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Unable to load the options of a choice from table '%s': %s",
+				lookup.TableName, err,
+			)
+		}
+		options[workflowType] = o
+	}
+	return options, nil
+}
+
+func (s *SqlBackend) queryOptions(ctx context.Context, query string) (options []*descriptor.Option, err error) {
+	log.When(config.Logging()).WithContext(ctx).Debugln(query)
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if !id.Valid {
+			continue
+		}
+		if !name.Valid {
+			name.String = id.String
+		}
+		options = append(options, &descriptor.Option{Id: id.String, Name: name.String})
+	}
+	return options, rows.Err()
+}
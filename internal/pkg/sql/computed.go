@@ -0,0 +1,128 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
+)
+
+// computedColumn is a computed field as it is selected by the
+// `TableSource` query template
+type computedColumn struct {
+	Column     string
+	Expression string
+}
+
+// tableSource returns what the queries reading a table select from. This
+// is the quoted name of the table, or the SQL query of a type descriptor
+// backed by a query. If the type descriptor has computed fields, it is a
+// derived table selecting the expressions of the computed fields as
+// additional columns, so that they can be filtered on and are part of the
+// table's schema mapping.
+func (s *SqlBackend) tableSource(ctx context.Context, table string) (string, error) {
+	cfg := config.Current(ctx)
+	return s.tableSourceFor(cfg.Database.Driver, cfg.Descriptor, table)
+}
+
+// tableSourceFor returns what the queries reading a table of the
+// descriptor d select from, see tableSource
+func (s *SqlBackend) tableSourceFor(driver string, d *descriptor.Descriptor, table string) (string, error) {
+	data := struct {
+		TableName string
+		Query     string
+		Computed  []*computedColumn
+	}{
+		TableName: table,
+	}
+	td := util.GetTypeDescriptorUsingDBTableName(
+		d.TypeDescriptors,
+		table,
+	)
+	if td != nil {
+		data.Query = td.Query
+		for _, field := range td.Fields {
+			if len(field.Expression) == 0 {
+				continue
+			}
+			expression, ok := field.Expression.For(driver)
+			if !ok {
+				return "", fmt.Errorf(
+					"computed field '%s' of type descriptor '%s' has no expression for the %s driver",
+					field.Key, td.Key, driver,
+				)
+			}
+			data.Computed = append(data.Computed, &computedColumn{
+				Column:     field.FromColumn,
+				Expression: expression,
+			})
+		}
+	}
+	sourceTemplate, err := template.New("TableSource").Parse(s.getQueryTemplate("TableSource"))
+	if err != nil {
+		return "", err
+	}
+	source := bytes.NewBufferString("")
+	if err := sourceTemplate.Execute(source, data); err != nil {
+		return "", err
+	}
+	return source.String(), nil
+}
+
+// fieldColumnType returns the golang type the values of a column are
+// scanned into when the database can not tell the type of the column,
+// like sqlite for the expressions of computed fields and for the columns
+// of queries. The type is derived from the workflow type of the field
+// reading the column.
+func fieldColumnType(d *descriptor.Descriptor, columnWithTable string) interface{} {
+	tableNamePrefix := strings.IndexRune(columnWithTable, '\x00')
+	if tableNamePrefix < 0 {
+		return nil
+	}
+	column := columnWithTable[tableNamePrefix+1:]
+	td := util.GetTypeDescriptorUsingDBTableName(
+		d.TypeDescriptors,
+		columnWithTable[0:tableNamePrefix],
+	)
+	if td == nil {
+		return nil
+	}
+	for _, field := range td.Fields {
+		if field.Type == nil {
+			continue
+		}
+		if field.Type.Name == "money" {
+			if field.Type.Amount != nil && field.Type.Amount.FromColumn == column {
+				return &sql.NullFloat64{}
+			}
+			if field.Type.Currency != nil && field.Type.Currency.FromColumn == column {
+				return &sql.NullString{}
+			}
+			continue
+		}
+		if field.FromColumn != column {
+			continue
+		}
+		switch field.Type.Name {
+		case "number":
+			return &sql.NullFloat64{}
+		case "boolean":
+			return &sql.NullBool{}
+		case "date":
+			return &util.NullTime{}
+		default:
+			return &sql.NullString{}
+		}
+	}
+	if td.ReadOnly() {
+		// Columns of views and queries that are not read by any field
+		return &sql.NullString{}
+	}
+	return nil
+}
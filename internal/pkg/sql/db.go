@@ -23,12 +23,13 @@ type NullTime struct {
 }
 
 func (s *SqlBackend) execContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
-	requestTx := ctx.Value(util.ContextKey("tx")).(string)
+	requestTx, _ := ctx.Value(util.ContextKey("tx")).(string)
 	if requestTx == "" {
 		// User has not specified an existing transaction to execute within.
 		// However, we will still run the exec statement within a new
 		// transaction
-		tx, err := s.DB.Begin()
+		var tx *sql.Tx
+		tx, err = s.DB.Begin()
 		if err != nil {
 			return nil, err
 		}
@@ -42,26 +43,49 @@ func (s *SqlBackend) execContext(ctx context.Context, query string, args ...inte
 				err = tx.Commit()
 			}
 		}()
-	} else {
-		// We assume the transacation is a valid one
-		txi, _ := s.Transactions.Load(requestTx)
-		tx := txi.(*sql.Tx)
 		result, err = tx.ExecContext(ctx, query, args...)
 		if err != nil {
 			return nil, err
 		}
-		tx.Commit()
+		if err = s.CheckRowFilter(ctx, tx, requestTx, result); err != nil {
+			return nil, err
+		}
 		return
 	}
-	result, err = s.DB.ExecContext(ctx, query, args...)
+	// We assume the transacation is a valid one. It is committed by
+	// the client once all of its statements were executed.
+	txi, _ := s.Transactions.Load(requestTx)
+	tx := txi.(*sql.Tx)
+	result, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	if err = s.CheckRowFilter(ctx, tx, requestTx, result); err != nil {
+		return nil, err
+	}
 	return
 }
 
+// CheckRowFilter runs the row filter check of a write operation, which is
+// passed in the context, within the transaction tx the row was written in.
+// If the written row does not match the row filter, the write is undone by
+// rolling back tx. This also ends the client's transaction requestTx, if
+// the write was executed within one.
+func (s *SqlBackend) CheckRowFilter(ctx context.Context, tx *sql.Tx, requestTx string, result sql.Result) error {
+	check, ok := ctx.Value(util.ContextKey("rowFilterCheck")).(util.RowFilterCheck)
+	if !ok {
+		return nil
+	}
+	err := check(ctx, tx, result)
+	if err != nil && requestTx != "" {
+		tx.Rollback()
+		s.DeleteTx(requestTx)
+	}
+	return err
+}
+
 func (s *SqlBackend) queryContext(ctx context.Context, query string, args ...interface{}) (results []interface{}, err error) {
-	currentRoute := ctx.Value(util.ContextKey("currentRoute")).(string)
+	currentRoute, _ := ctx.Value(util.ContextKey("currentRoute")).(string)
 	switch currentRoute {
 	case "GetSingleAsOption", "GetCollectionAsOptions":
 		return s.queryContextForOptionRoutes(ctx, query, args...)
@@ -102,7 +126,7 @@ func (s *SqlBackend) queryContextForNonOptionRoutes(ctx context.Context, query s
 	results = deduplicateSingleResource(
 		results,
 		util.GetTypeDescriptorUsingDBTableName(
-			config.Options.Descriptor.TypeDescriptors,
+			config.Current(ctx).Descriptor.TypeDescriptors,
 			table,
 		),
 	)
@@ -139,7 +163,7 @@ func (s *SqlBackend) createTx(timeout time.Duration) (txUUID uuid.UUID, err erro
 		return uuid.UUID{}, err
 	}
 	s.StoreTx(fmt.Sprintf("%s", txUUID), tx)
-	log.When(config.Options.Logging).Infof("[handler] added transaction %s to backend\n", txUUID)
+	log.When(config.Logging()).Infof("[handler] added transaction %s to backend\n", txUUID)
 	// Explicitly call cancel after delay
 	go func(c context.CancelFunc, d time.Duration, id uuid.UUID) {
 		select {
@@ -147,8 +171,8 @@ func (s *SqlBackend) createTx(timeout time.Duration) (txUUID uuid.UUID, err erro
 			c()
 			_, ok := s.Transactions.Load(fmt.Sprintf("%s", id))
 			if ok {
-				s.Transactions.Delete(id)
-				log.When(config.Options.Logging).Infof("[handler] timeout expired: \n"+
+				s.Transactions.Delete(fmt.Sprintf("%s", id))
+				log.When(config.Logging()).Infof("[handler] timeout expired: \n"+
 					"transaction %s has been deleted from backend\n", id)
 			}
 		}
@@ -168,6 +192,35 @@ func (s *SqlBackend) commitTx(txUUID string) (err error) {
 	return
 }
 
+// close rolls back the transactions that were not committed by the clients
+// and closes the connection to the database
+func (s *SqlBackend) close() error {
+	s.Transactions.Range(func(key, value interface{}) bool {
+		s.DeleteTx(key)
+		if err := value.(*sql.Tx).Rollback(); err != nil && err != sql.ErrTxDone {
+			log.When(config.Logging()).Errorf(
+				"[backend] unable to roll back transaction %s: %s\n", key, err,
+			)
+			return true
+		}
+		log.When(config.Logging()).Warnf(
+			"[backend] rolled back transaction %s: "+
+				"workflow connector is shutting down before it was committed\n",
+			key,
+		)
+		return true
+	})
+	if s.stopRefresh != nil {
+		close(s.stopRefresh)
+		s.stopRefresh = nil
+	}
+	if s.DB == nil {
+		return nil
+	}
+	log.When(config.Logging()).Infoln("[backend] close connection to database")
+	return s.DB.Close()
+}
+
 func rowsToResults(rows *sql.Rows, columnNames []string, dataTypes []interface{}) (results []interface{}, err error) {
 	for rows.Next() {
 		result, err := processRow(rows, columnNames, dataTypes)
@@ -232,7 +285,7 @@ func deduplicateSingleResource(data []interface{}, td *descriptor.TypeDescriptor
 					fieldResultSet = util.AppendNoDuplicates(fieldResultSet, tableResults)
 				}
 			}
-			data[i].(map[string]interface{})[td.TableName].(map[string]interface{})[field.Key] = map[string]interface{}{
+			data[i].(map[string]interface{})[td.Table()].(map[string]interface{})[field.Key] = map[string]interface{}{
 				field.Relationship.WithTable: fieldResultSet,
 			}
 		}
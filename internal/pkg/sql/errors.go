@@ -0,0 +1,29 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// IsTransientError reports whether err is a connection error that may not
+// occur again when the call is retried, for example while the database
+// fails over to a standby. Errors specific to a database, such as
+// deadlocks, are classified by the dialects.
+func IsTransientError(err error) bool {
+	// Context errors implement net.Error, but are caused by the request
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,50 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Explainer returns a function that explains a query by prepending the
+// given statement, for example `EXPLAIN `, and returns the rows of the
+// query plan with their columns separated by tabs
+func (s *SqlBackend) Explainer(statement string) func(context.Context, string, ...interface{}) (string, error) {
+	return func(ctx context.Context, query string, args ...interface{}) (string, error) {
+		rows, err := s.DB.QueryContext(ctx, statement+query, args...)
+		if err != nil {
+			return "", err
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			return "", err
+		}
+		var plan []string
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		for rows.Next() {
+			if err := rows.Scan(values...); err != nil {
+				return "", err
+			}
+			line := make([]string, len(values))
+			for i, value := range values {
+				switch v := (*value.(*interface{})).(type) {
+				case []byte:
+					line[i] = string(v)
+				case nil:
+					line[i] = "NULL"
+				default:
+					line[i] = fmt.Sprintf("%v", v)
+				}
+			}
+			plan = append(plan, strings.Join(line, "\t"))
+		}
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return strings.Join(plan, "\n"), nil
+	}
+}
@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// IntrospectionQueries read the schema of the tables in the current database
+var IntrospectionQueries = introspect.Queries{
+	Columns: "SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE " +
+		"FROM information_schema.COLUMNS c " +
+		"JOIN information_schema.TABLES t " +
+		"ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME " +
+		"WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE' " +
+		"ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION",
+	PrimaryKeys: "SELECT TABLE_NAME, COLUMN_NAME " +
+		"FROM information_schema.KEY_COLUMN_USAGE " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND CONSTRAINT_NAME = 'PRIMARY' " +
+		"ORDER BY TABLE_NAME, ORDINAL_POSITION",
+	ForeignKeys: "SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, " +
+		"REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME " +
+		"FROM information_schema.KEY_COLUMN_USAGE " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL",
+}
+
+// Introspect reads the tables, columns, primary keys and foreign keys of
+// the current database
+func Introspect(ctx context.Context, db *sql.DB) ([]*introspect.Table, error) {
+	return introspect.Query(ctx, db, IntrospectionQueries)
+}
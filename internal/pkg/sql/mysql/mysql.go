@@ -2,9 +2,10 @@ package mysql
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
@@ -17,20 +18,26 @@ const (
 var (
 	QueryTemplates = map[string]string{
 		"GetSingle": "SELECT * " +
-			"FROM `{{.TableName}}` AS `_{{.TableName}}`" +
+			"FROM {{source .TableName}} AS `_{{.TableName}}`" +
 			"{{range .Relations}}" +
-			"   LEFT JOIN `{{.Relationship.WithTable}}`" +
+			"   LEFT JOIN {{if rowFilter .Relationship.WithTable}}" +
+			"(SELECT * FROM {{source .Relationship.WithTable}} `{{.Relationship.WithTable}}` WHERE {{rowFilter .Relationship.WithTable}})" +
+			"{{else}}{{source .Relationship.WithTable}}{{end}} `{{.Relationship.WithTable}}`" +
 			"   ON `{{.Relationship.WithTable}}`.`{{.Relationship.ForeignTableUniqueIdColumn}}`" +
 			"   = `_{{$.TableName}}`.`{{.Relationship.LocalTableUniqueIdColumn}}`" +
 			"{{end}}" +
-			" WHERE `_{{$.TableName}}`.`{{.UniqueIdColumn}}` = ?",
+			" WHERE `_{{$.TableName}}`.`{{.UniqueIdColumn}}` = ?" +
+			"{{with .RowFilter}} AND `_{{$.TableName}}`.`{{$.UniqueIdColumn}}` IN (SELECT `{{$.UniqueIdColumn}}` FROM {{source $.TableName}} `{{$.TableName}}` WHERE {{.}}){{end}}",
 		"GetSingleAsOption": "SELECT `{{.UniqueIdColumn}}`, `{{.ColumnAsOptionName}}` " +
-			"FROM `{{.TableName}}` " +
-			"WHERE `{{.UniqueIdColumn}}` = ?",
+			"FROM {{source .TableName}} AS `{{.TableName}}` " +
+			"WHERE `{{.UniqueIdColumn}}` = ?" +
+			"{{with .RowFilter}} AND ({{.}}){{end}}",
 		"GetCollection": "SELECT * " +
-			"FROM `{{.TableName}}` AS `_{{.TableName}}`" +
+			"FROM {{source .TableName}} AS `_{{.TableName}}`" +
 			"{{range .Relations}}" +
-			"   LEFT JOIN `{{.Relationship.WithTable}}`" +
+			"   LEFT JOIN {{if rowFilter .Relationship.WithTable}}" +
+			"(SELECT * FROM {{source .Relationship.WithTable}} `{{.Relationship.WithTable}}` WHERE {{rowFilter .Relationship.WithTable}})" +
+			"{{else}}{{source .Relationship.WithTable}}{{end}} `{{.Relationship.WithTable}}`" +
 			"   ON `{{.Relationship.WithTable}}`.`{{.Relationship.ForeignTableUniqueIdColumn}}`" +
 			"   = `_{{$.TableName}}`.`{{.Relationship.LocalTableUniqueIdColumn}}` " +
 			"{{end}}" +
@@ -40,26 +47,39 @@ var (
 			"      AND `_{{$.TableName}}`.`{{$element}}` = ? " +
 			"   {{end}}" +
 			"{{end}}" +
+			"{{with .RowFilter}}{{if $.ColumnNames}} AND{{else}} WHERE{{end}} `_{{$.TableName}}`.`{{$.UniqueIdColumn}}` IN (SELECT `{{$.UniqueIdColumn}}` FROM {{source $.TableName}} `{{$.TableName}}` WHERE {{.}}) {{end}}" +
 			"ORDER BY `_{{.TableName}}`.`{{.UniqueIdColumn}}` ASC",
 		"GetCollectionAsOptions": "SELECT `{{.UniqueIdColumn}}`, `{{.ColumnAsOptionName}}` " +
-			"FROM `{{.TableName}}` " +
+			"FROM {{source .TableName}} AS `{{.TableName}}` " +
 			"WHERE `{{.ColumnAsOptionName}}` LIKE ? " +
 			"{{range $index, $element := .ColumnNames}}" +
 			"   AND `{{$.TableName}}`.`{{$element}}` = ? " +
 			"{{end}}" +
+			"{{with .RowFilter}} AND ({{.}}) {{end}}" +
 			"ORDER BY `{{.TableName}}`.`{{.UniqueIdColumn}}` ASC",
 		"UpdateSingle": "UPDATE `{{.TableName}}` SET `{{.ColumnNames | head}}`" +
-			" = ?{{range .ColumnNames | tail}}, `{{.}}` = ?{{end}} WHERE `{{.UniqueIdColumn}}` = ?",
+			" = ?{{range .ColumnNames | tail}}, `{{.}}` = ?{{end}} WHERE `{{.UniqueIdColumn}}` = ?" +
+			"{{with .RowFilter}} AND ({{.}}){{end}}",
 		"CreateSingle": "INSERT INTO `{{.TableName}}`(`{{.ColumnNames | head}}`" +
 			"{{range .ColumnNames | tail}}, `{{.}}`{{end}}) " +
 			"VALUES(?{{range .ColumnNames | tail}}, ?{{end}})",
-		"DeleteSingle": "DELETE FROM `{{.TableName}}` WHERE `{{.UniqueIdColumn}}` = ?",
+		"DeleteSingle": "DELETE FROM `{{.TableName}}` WHERE `{{.UniqueIdColumn}}` = ?" +
+			"{{with .RowFilter}} AND ({{.}}){{end}}",
 		"GetTableSchema": "SELECT * " +
-			"FROM `{{.TableName}}` " +
+			"FROM {{source .TableName}} AS `{{.TableName}}` " +
 			"LIMIT 1",
-		"GetTableWithRelationshipsSchema": "SELECT * FROM `{{.TableName}}` AS `_{{.TableName}}`" +
+		"GetChoiceOptions": "SELECT `{{.IdColumn}}`, `{{.NameColumn}}` " +
+			"FROM `{{.TableName}}` " +
+			"ORDER BY `{{.NameColumn}}`",
+		"CheckRowFilter": "SELECT COUNT(*) FROM {{source .TableName}} `{{.TableName}}` " +
+			"WHERE `{{.UniqueIdColumn}}` = ? AND ({{.RowFilter}})",
+		"TableSource": "{{if .Computed}}(SELECT `{{.TableName}}`.*" +
+			"{{range .Computed}}, ({{.Expression}}) AS `{{.Column}}`{{end}}" +
+			" FROM {{if .Query}}({{.Query}}) {{end}}`{{.TableName}}`)" +
+			"{{else if .Query}}({{.Query}}){{else}}`{{.TableName}}`{{end}}",
+		"GetTableWithRelationshipsSchema": "SELECT * FROM {{source .TableName}} AS `_{{.TableName}}`" +
 			"{{range .Relations}}" +
-			" LEFT JOIN `{{.Relationship.WithTable}}`" +
+			" LEFT JOIN {{source .Relationship.WithTable}} `{{.Relationship.WithTable}}`" +
 			" ON `{{.Relationship.WithTable}}`.`{{.Relationship.ForeignTableUniqueIdColumn}}`" +
 			" = `_{{$.TableName}}`.`{{.Relationship.LocalTableUniqueIdColumn}}`{{end}} LIMIT 1",
 	}
@@ -106,6 +126,11 @@ func New() endpoint.Endpoint {
 	m := &Mysql{sqlBackend.New().(*sqlBackend.SqlBackend)}
 	m.Templates = QueryTemplates
 	m.CastBackendTypeToGolangType = convertFromMysqlDataType
+	m.IsTransientErrorFunc = isTransientError
+	m.IsConflictErrorFunc = isConflictError
+	m.Introspect = Introspect
+	m.ExplainFunc = m.Explainer("EXPLAIN ")
+	m.ExecProcedureFunc = m.execProcedure
 	return m
 }
 
@@ -133,3 +158,39 @@ func isOfDataType(ts []string, fieldDataType string) (result bool) {
 	}
 	return
 }
+
+// isTransientError classifies deadlocks, lock wait timeouts and a server
+// that is overloaded or shutting down as transient
+func isTransientError(err error) bool {
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // too many connections
+			1053, // server shutdown in progress
+			1205, // lock wait timeout exceeded
+			1213: // deadlock found when trying to get lock
+			return true
+		}
+		return false
+	}
+	return sqlBackend.IsTransientError(err)
+}
+
+// isConflictError classifies duplicate keys and foreign key constraint
+// violations as conflicts
+func isConflictError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1062, // duplicate entry for key
+			1451, // cannot delete or update a parent row
+			1452, // cannot add or update a child row
+			1586: // duplicate entry for key
+			return true
+		}
+	}
+	return false
+}
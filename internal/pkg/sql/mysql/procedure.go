@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
+)
+
+// execProcedure calls the stored procedure of an action. Output parameters
+// are bound to session variables, which are selected afterwards on the
+// same connection.
+func (m *Mysql) execProcedure(ctx context.Context, action *descriptor.Action, args ...interface{}) ([]map[string]interface{}, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	placeholders := m.ProcedureCall(action)
+	if action.ResultSet {
+		query := fmt.Sprintf("CALL %s(%s)", action.Procedure, strings.Join(placeholders, ", "))
+		log.When(config.Logging()).WithContext(ctx).Debugln(query)
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return sqlBackend.ScanProcedureRows(rows, action)
+	}
+	var variables []string
+	for i := range action.Output {
+		variables = append(variables, fmt.Sprintf("@wfc_output_%d", i))
+	}
+	query := fmt.Sprintf(
+		"CALL %s(%s)",
+		action.Procedure, strings.Join(append(placeholders, variables...), ", "),
+	)
+	log.When(config.Logging()).WithContext(ctx).Debugln(query)
+	if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	if len(variables) == 0 {
+		return nil, nil
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT "+strings.Join(variables, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlBackend.ScanProcedureRows(rows, action)
+}
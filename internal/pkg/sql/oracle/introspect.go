@@ -0,0 +1,48 @@
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// IntrospectionQueries read the schema of the tables owned by the current
+// user
+var IntrospectionQueries = introspect.Queries{
+	Columns: "SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE " +
+		"FROM USER_TAB_COLUMNS c " +
+		"JOIN USER_TABLES t ON t.TABLE_NAME = c.TABLE_NAME " +
+		"ORDER BY c.TABLE_NAME, c.COLUMN_ID",
+	PrimaryKeys: "SELECT cc.TABLE_NAME, cc.COLUMN_NAME " +
+		"FROM USER_CONSTRAINTS c " +
+		"JOIN USER_CONS_COLUMNS cc ON cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME " +
+		"WHERE c.CONSTRAINT_TYPE = 'P' " +
+		"ORDER BY cc.TABLE_NAME, cc.POSITION",
+	ForeignKeys: "SELECT c.CONSTRAINT_NAME, cc.TABLE_NAME, cc.COLUMN_NAME, " +
+		"rc.TABLE_NAME, rc.COLUMN_NAME " +
+		"FROM USER_CONSTRAINTS c " +
+		"JOIN USER_CONS_COLUMNS cc ON cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME " +
+		"JOIN USER_CONS_COLUMNS rc " +
+		"ON rc.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME AND rc.POSITION = cc.POSITION " +
+		"WHERE c.CONSTRAINT_TYPE = 'R'",
+}
+
+// Introspect reads the tables, columns, primary keys and foreign keys
+// owned by the current user
+func Introspect(ctx context.Context, db *sql.DB) ([]*introspect.Table, error) {
+	tables, err := introspect.Query(ctx, db, IntrospectionQueries)
+	if err != nil {
+		return nil, err
+	}
+	// Oracle's DATE contains the time of day as well
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			if strings.EqualFold(column.DataType, "DATE") {
+				column.DataType = "DATETIME"
+			}
+		}
+	}
+	return tables, nil
+}
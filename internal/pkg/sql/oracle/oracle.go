@@ -11,6 +11,7 @@ import (
 	"github.com/signavio/workflow-connector/internal/pkg/config"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
 	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/query"
 	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
 	"golang.org/x/text/encoding"
@@ -45,20 +46,26 @@ var (
 	EuroSymbolSupport characterSet = charmap.Windows1252
 	QueryTemplates                 = map[string]string{
 		`GetSingle`: `SELECT * ` +
-			`FROM "{{.TableName}}" "_{{.TableName}}"` +
+			`FROM {{source .TableName}} "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"` +
 			`{{end}}` +
-			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = :1`,
+			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = :1` +
+			`{{with .RowFilter}} AND "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetSingleAsOption`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
-			`WHERE "{{.UniqueIdColumn}}" = :1`,
+			`FROM {{source .TableName}} "_{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = :1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetCollection`: `SELECT * ` +
-			`FROM "{{.TableName}}" "_{{.TableName}}"` +
+			`FROM {{source .TableName}} "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}} "` +
 			`{{end}}` +
@@ -67,13 +74,15 @@ var (
 			`   {{range $index, $element := . | tail}}` +
 			`      AND "_{{$.TableName}}"."{{$element}}" = {{(format $index $element)}} ` +
 			`   {{end}}` +
-			`{{end}}`,
+			`{{end}}` +
+			`{{with .RowFilter}}{{if $.ColumnNames}} AND{{else}} WHERE{{end}} "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetCollectionAsOptions`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} "_{{.TableName}}" ` +
 			`WHERE UPPER("{{.ColumnAsOptionName}}") LIKE '%'||UPPER(:1)||'%' ` +
 			`{{range $index, $element := .ColumnNames}}` +
 			`   AND "_{{$.TableName}}"."{{$element}}" = {{(format $index $element)}} ` +
-			`{{end}}`,
+			`{{end}}` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`UpdateSingle`: `UPDATE "{{.TableName}}" ` +
 			`{{with $firstColumn := .ColumnNames | head}}` +
 			`SET "{{$firstColumn}}" = {{(format -1 $firstColumn)}}` +
@@ -81,7 +90,8 @@ var (
 			`{{range $index, $element := .ColumnNames | tail}},` +
 			`  "{{$element}}" = {{(format $index $element)}}` +
 			`{{end}} ` +
-			`WHERE "{{.UniqueIdColumn}}"= :{{(lenPlus1 .ColumnNames)}}`,
+			`WHERE "{{.UniqueIdColumn}}"= :{{(lenPlus1 .ColumnNames)}}` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`CreateSingle`: `DECLARE "l_{{.UniqueIdColumn}}" nvarchar2(256); ` +
 			`BEGIN ` +
 			`INSERT INTO "{{.TableName}}"` +
@@ -97,13 +107,23 @@ var (
 			`{{end}}) RETURNING "{{.UniqueIdColumn}}" INTO "l_{{.UniqueIdColumn}}"; ` +
 			`DBMS_OUTPUT.PUT_LINE("l_{{.UniqueIdColumn}}"); ` +
 			"END;",
-		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = :1`,
+		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = :1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetTableSchema`: `SELECT * ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} "{{.TableName}}" ` +
 			`WHERE ROWNUM <= 1`,
-		`GetTableWithRelationshipsSchema`: `SELECT * FROM "{{.TableName}}" "_{{.TableName}}"` +
+		`GetChoiceOptions`: `SELECT "{{.IdColumn}}", "{{.NameColumn}}" ` +
+			`FROM "{{.TableName}}" ` +
+			`ORDER BY "{{.NameColumn}}"`,
+		`CheckRowFilter`: `SELECT COUNT(*) FROM {{source .TableName}} "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = :1 AND ({{.RowFilter}})`,
+		`TableSource`: `{{if .Computed}}(SELECT "{{.TableName}}".*` +
+			`{{range .Computed}}, ({{.Expression}}) AS "{{.Column}}"{{end}}` +
+			` FROM {{if .Query}}({{.Query}}) {{end}}"{{.TableName}}")` +
+			`{{else if .Query}}({{.Query}}){{else}}"{{.TableName}}"{{end}}`,
+		`GetTableWithRelationshipsSchema`: `SELECT * FROM {{source .TableName}} "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			` LEFT JOIN "{{.Relationship.WithTable}}"` +
+			` LEFT JOIN {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}"` +
 			` ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			` = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"{{end}} WHERE ROWNUM <= 1`,
 	}
@@ -218,6 +238,23 @@ var (
 		}
 		return
 	}
+	// Oracle databases prior to 23c have no boolean data type, so
+	// booleans are written as 1 and 0 to NUMBER(1) columns
+	oracleBooleanArgFunc = func(requestData map[string]interface{}, field *descriptor.Field) (result interface{}, ok bool, err error) {
+		if result, ok = requestData[field.Key]; ok {
+			result, err = query.CoerceValue(field.Type, result)
+			if err != nil {
+				return nil, ok, fmt.Errorf("field '%s': %w", field.Key, err)
+			}
+			if b, isBool := result.(bool); isBool {
+				if b {
+					return 1, ok, nil
+				}
+				return 0, ok, nil
+			}
+		}
+		return
+	}
 )
 
 func (l *lastId) LastInsertId() (int64, error) {
@@ -236,36 +273,51 @@ func New() endpoint.Endpoint {
 	oracleSpecificArgFuncs["datetime"] = oracleDateTimeArgFunc
 	oracleSpecificArgFuncs["date"] = oracleDateArgFunc
 	oracleSpecificArgFuncs["time"] = oracleTimeArgFunc
+	oracleSpecificArgFuncs["boolean"] = oracleBooleanArgFunc
 	o.CoerceArgFuncs = oracleSpecificArgFuncs
 	o.QueryFormatFuncs = oracleQueryFormatFuncs
+	o.FormatPlaceholder = func(position int) string {
+		return fmt.Sprintf(":%d", position)
+	}
 	o.NewSchemaMapping = o.newOracleSchemaMapping
 	o.OpenFunc = o.Open
+	o.IsTransientErrorFunc = isTransientError
+	o.IsConflictErrorFunc = isConflictError
+	o.Introspect = Introspect
+	o.ExecProcedureFunc = o.execProcedure
 	return o
 }
 func (o *Oracle) Open(args ...interface{}) error {
 	driver := args[0].(string)
 	url := args[1].(string)
-	log.When(config.Options.Logging).Infof(
-		"[backend] open connection to database %v\n",
+	log.When(config.Logging()).Infof(
+		"[backend] open connection to database %v at %s\n",
 		driver,
+		config.Redact(url),
 	)
 	db, err := sql.Open(driver, url)
 	if err != nil {
 		return fmt.Errorf("Error opening connection to database: %s", err)
 	}
 	o.DB = db
+	o.ConfigurePool()
+	o.RegisterMetrics(driver)
 	if err := driverSpecificInitialization(context.Background(), o); err != nil {
 		return fmt.Errorf("Error performing driver specific initialization: %s", err)
 	}
 	o.QueryContextFunc = wrapQueryContext(o.characterSet, o.QueryContextFunc)
 	o.ExecContextFunc = wrapExecContext(o, o.ExecContextFunc)
+	if err := o.LoadChoiceOptions(context.Background()); err != nil {
+		return err
+	}
 	err = o.SaveSchemaMapping()
 	if err != nil {
 		return fmt.Errorf("Error saving table schema: %s", err)
 	}
+	o.RefreshChoiceOptions()
 	return nil
 }
-func (o *Oracle) newOracleSchemaMapping(columnsWithTable []string, columnTypes []*sql.ColumnType) (*descriptor.SchemaMapping, error) {
+func (o *Oracle) newOracleSchemaMapping(d *descriptor.Descriptor, columnsWithTable []string, columnTypes []*sql.ColumnType) (*descriptor.SchemaMapping, error) {
 	var backendTypes, golangTypes, workflowTypes []interface{}
 	var fieldNames []string
 	for i := range columnTypes {
@@ -291,7 +343,7 @@ func (o *Oracle) newOracleSchemaMapping(columnsWithTable []string, columnTypes [
 				"unable to get the native golang type",
 			)
 		}
-		workflowType := sqlBackend.GetWorkflowType(columnsWithTable[i])
+		workflowType := sqlBackend.GetWorkflowType(d, columnsWithTable[i])
 		if workflowType == nil {
 			return nil, fmt.Errorf(
 				"unable to get the workflow type specified in descriptor.json",
@@ -305,13 +357,13 @@ func (o *Oracle) newOracleSchemaMapping(columnsWithTable []string, columnTypes [
 	return &descriptor.SchemaMapping{fieldNames, backendTypes, golangTypes, workflowTypes}, nil
 }
 func (o *Oracle) setCharacterSet() (err error) {
-	log.When(config.Options.Logging).Infoln("[oracle] query characterset in use by db")
+	log.When(config.Logging()).Infoln("[oracle] query characterset in use by db")
 	getCharacterSet :=
 		`SELECT VALUE FROM NLS_DATABASE_PARAMETERS WHERE PARAMETER = 'NLS_CHARACTERSET'`
 	var charSet string
 	err = o.DB.QueryRowContext(context.Background(), getCharacterSet).Scan(&charSet)
 	if err != nil {
-		log.When(config.Options.Logging).Infof("Error retrieving current character encoding from db: %s", err)
+		log.When(config.Logging()).Errorf("[oracle] error retrieving current character encoding from db: %s\n", err)
 		return fmt.Errorf("Error retrieving current character encoding from db: %s", err)
 	}
 	switch charSet {
@@ -326,7 +378,7 @@ func (o *Oracle) setCharacterSet() (err error) {
 	return nil
 }
 func driverSpecificInitialization(ctx context.Context, o *Oracle) error {
-	log.When(config.Options.Logging).Infoln("[oracle] performing driver specific initialization")
+	log.When(config.Logging()).Infoln("[oracle] performing driver specific initialization")
 	if err := godror.EnableDbmsOutput(ctx, o.DB); err != nil {
 		return err
 	}
@@ -346,7 +398,7 @@ func (o *Oracle) setSessionTimeZone() error {
 	if err != nil {
 		return err
 	}
-	log.When(config.Options.Logging).
+	log.When(config.Logging()).
 		Infof("[oracle] current session time zone is: %s\n", sessionTimeZone)
 	parsedTime, err := time.Parse("-07:00", sessionTimeZone)
 	if err != nil {
@@ -442,3 +494,43 @@ func isOfDataType(ts []string, fieldDataType string) (result bool) {
 func chomp(s string) string {
 	return s[0:strings.IndexRune(s, '\n')]
 }
+
+// isTransientError classifies deadlocks, serialization failures, lost
+// connections, unreachable listeners and a database that is starting up
+// or shutting down as transient
+func isTransientError(err error) bool {
+	if oraErr, ok := godror.AsOraErr(err); ok {
+		switch oraErr.Code() {
+		case 60, // deadlock detected
+			1033,  // initialization or shutdown in progress
+			1034,  // not available
+			1089,  // immediate shutdown in progress
+			3113,  // end-of-file on communication channel
+			3114,  // not connected
+			3135,  // connection lost contact
+			8177,  // can't serialize access for this transaction
+			12170, // connect timeout occurred
+			12514, // listener does not currently know of service
+			12528, // all appropriate instances are blocking new connections
+			12537, // connection closed
+			12541: // no listener
+			return true
+		}
+		return false
+	}
+	return sqlBackend.IsTransientError(err)
+}
+
+// isConflictError classifies unique and referential integrity constraint
+// violations as conflicts
+func isConflictError(err error) bool {
+	if oraErr, ok := godror.AsOraErr(err); ok {
+		switch oraErr.Code() {
+		case 1, // unique constraint violated
+			2291, // integrity constraint violated, parent key not found
+			2292: // integrity constraint violated, child record found
+			return true
+		}
+	}
+	return false
+}
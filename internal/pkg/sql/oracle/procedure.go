@@ -0,0 +1,79 @@
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/godror/godror"
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
+)
+
+// execProcedure calls the PL/SQL procedure of an action in an anonymous
+// block. Output parameters are bound as out-binds following the action's
+// parameters, and a result set is returned by the procedure in a
+// SYS_REFCURSOR out parameter following those.
+func (o *Oracle) execProcedure(ctx context.Context, action *descriptor.Action, args ...interface{}) ([]map[string]interface{}, error) {
+	conn, err := o.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	placeholders := o.ProcedureCall(action)
+	args = append([]interface{}{}, args...)
+	var destinations []interface{}
+	var resultSet driver.Rows
+	if action.ResultSet {
+		placeholders = append(placeholders, o.FormatPlaceholder(len(placeholders)+1))
+		args = append(args, sql.Out{Dest: &resultSet})
+	} else {
+		for _, output := range action.Output {
+			destination := outputDestination(output)
+			destinations = append(destinations, destination)
+			placeholders = append(placeholders, o.FormatPlaceholder(len(placeholders)+1))
+			args = append(args, sql.Out{Dest: destination})
+		}
+	}
+	query := fmt.Sprintf("BEGIN %s(%s); END;", action.Procedure, strings.Join(placeholders, ", "))
+	log.When(config.Logging()).WithContext(ctx).Debugln(query)
+	if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	if action.ResultSet {
+		rows, err := godror.WrapRows(ctx, conn, resultSet)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return sqlBackend.ScanProcedureRows(rows, action)
+	}
+	result := make(map[string]interface{})
+	for i, output := range action.Output {
+		value := sqlBackend.ProcedureValue(output, destinations[i])
+		// Oracle does not distinguish empty strings from NULL
+		if value == "" {
+			value = nil
+		}
+		result[output.Key] = value
+	}
+	return []map[string]interface{}{result}, nil
+}
+
+// outputDestination returns the out-bind of an output parameter. Booleans
+// are read as numbers, since Oracle databases prior to 23c store them as
+// NUMBER(1).
+func outputDestination(output *descriptor.Parameter) interface{} {
+	switch output.Type.Name {
+	case "number", "boolean":
+		return &sql.NullFloat64{}
+	case "date":
+		return &godror.NullTime{}
+	default:
+		return new(string)
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// IntrospectionQueries read the schema of the tables in the current schema
+var IntrospectionQueries = introspect.Queries{
+	Columns: `SELECT c.table_name, c.column_name, c.data_type ` +
+		`FROM information_schema.columns c ` +
+		`JOIN information_schema.tables t ` +
+		`ON t.table_schema = c.table_schema AND t.table_name = c.table_name ` +
+		`WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE' ` +
+		`ORDER BY c.table_name, c.ordinal_position`,
+	PrimaryKeys: `SELECT kcu.table_name, kcu.column_name ` +
+		`FROM information_schema.table_constraints tc ` +
+		`JOIN information_schema.key_column_usage kcu ` +
+		`ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name ` +
+		`WHERE tc.table_schema = current_schema() AND tc.constraint_type = 'PRIMARY KEY' ` +
+		`ORDER BY kcu.table_name, kcu.ordinal_position`,
+	ForeignKeys: `SELECT kcu.constraint_name, kcu.table_name, kcu.column_name, ` +
+		`ref.table_name, ref.column_name ` +
+		`FROM information_schema.referential_constraints rc ` +
+		`JOIN information_schema.key_column_usage kcu ` +
+		`ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name ` +
+		`JOIN information_schema.key_column_usage ref ` +
+		`ON ref.constraint_schema = rc.unique_constraint_schema ` +
+		`AND ref.constraint_name = rc.unique_constraint_name ` +
+		`AND ref.ordinal_position = kcu.position_in_unique_constraint ` +
+		`WHERE kcu.table_schema = current_schema()`,
+}
+
+// Introspect reads the tables, columns, primary keys and foreign keys of
+// the current schema
+func Introspect(ctx context.Context, db *sql.DB) ([]*introspect.Table, error) {
+	return introspect.Query(ctx, db, IntrospectionQueries)
+}
@@ -3,9 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
@@ -18,20 +20,26 @@ type lastId struct {
 var (
 	QueryTemplates = map[string]string{
 		`GetSingle`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"` +
 			`{{end}}` +
-			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = $1`,
+			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = $1` +
+			`{{with .RowFilter}} AND "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetSingleAsOption`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
-			`WHERE "{{.UniqueIdColumn}}" = $1`,
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = $1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetCollection`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}" ` +
 			`{{end}}` +
@@ -41,13 +49,15 @@ var (
 			`      AND "_{{$.TableName}}"."{{$element}}" = ${{(add2 $index)}} ` +
 			`   {{end}}` +
 			`{{end}}` +
+			`{{with .RowFilter}}{{if $.ColumnNames}} AND{{else}} WHERE{{end}} "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}) {{end}}` +
 			`ORDER BY "_{{.TableName}}"."{{.UniqueIdColumn}}" ASC`,
 		`GetCollectionAsOptions`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
 			`WHERE CAST ("{{.ColumnAsOptionName}}" AS TEXT) ILIKE $1 ` +
 			`{{range $index, $element := .ColumnNames}}` +
 			`   AND "{{$.TableName}}"."{{$element}}" = ${{(add2 $index)}} ` +
 			`{{end}} ` +
+			`{{with .RowFilter}} AND ({{.}}) {{end}}` +
 			`ORDER BY "{{.TableName}}"."{{.UniqueIdColumn}}" ASC`,
 		`UpdateSingle`: `UPDATE "{{.TableName}}" ` +
 			`SET "{{.ColumnNames | head}}" = $1` +
@@ -55,6 +65,7 @@ var (
 			`  "{{$element}}" = ${{(add2 $index)}}` +
 			`{{end}} ` +
 			`WHERE "{{.UniqueIdColumn}}" = ${{(lenPlus1 .ColumnNames)}} ` +
+			`{{with .RowFilter}} AND ({{.}}) {{end}}` +
 			`RETURNING "{{.UniqueIdColumn}}"`,
 		`CreateSingle`: `INSERT INTO "{{.TableName}}"` +
 			`("{{.ColumnNames | head}}"` +
@@ -66,13 +77,23 @@ var (
 			`  ${{$index | add2}}` +
 			`{{end}}) ` +
 			`RETURNING "{{.UniqueIdColumn}}"`,
-		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = $1`,
+		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = $1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetTableSchema`: `SELECT * ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
 			`LIMIT 1`,
-		`GetTableWithRelationshipsSchema`: `SELECT * FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+		`GetChoiceOptions`: `SELECT "{{.IdColumn}}", "{{.NameColumn}}" ` +
+			`FROM "{{.TableName}}" ` +
+			`ORDER BY "{{.NameColumn}}"`,
+		`CheckRowFilter`: `SELECT COUNT(*) FROM {{source .TableName}} "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = $1 AND ({{.RowFilter}})`,
+		`TableSource`: `{{if .Computed}}(SELECT "{{.TableName}}".*` +
+			`{{range .Computed}}, ({{.Expression}}) AS "{{.Column}}"{{end}}` +
+			` FROM {{if .Query}}({{.Query}}) {{end}}"{{.TableName}}")` +
+			`{{else if .Query}}({{.Query}}){{else}}"{{.TableName}}"{{end}}`,
+		`GetTableWithRelationshipsSchema`: `SELECT * FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			` LEFT JOIN "{{.Relationship.WithTable}}"` +
+			` LEFT JOIN {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}"` +
 			` ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			` = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"{{end}} LIMIT 1`,
 	}
@@ -111,8 +132,10 @@ func (l *lastId) LastInsertId() (int64, error) {
 	return l.id, nil
 }
 
+// RowsAffected is always 1, since the id of the row is only known if a
+// row was returned by the RETURNING clause
 func (l *lastId) RowsAffected() (int64, error) {
-	return 0, nil
+	return 1, nil
 }
 
 func New() endpoint.Endpoint {
@@ -120,19 +143,30 @@ func New() endpoint.Endpoint {
 	p.ExecContextFunc = execContext(p.SqlBackend)
 	p.Templates = QueryTemplates
 	p.CastBackendTypeToGolangType = convertFromPostgresDataType
+	p.FormatPlaceholder = func(position int) string {
+		return fmt.Sprintf("$%d", position)
+	}
+	p.IsTransientErrorFunc = isTransientError
+	p.IsConflictErrorFunc = isConflictError
+	p.Introspect = Introspect
+	p.ExplainFunc = p.Explainer("EXPLAIN ")
+	p.ExecProcedureFunc = p.execProcedure
 	return p
 }
 
 func execContext(b *sqlBackend.SqlBackend) func(context.Context, string, ...interface{}) (sql.Result, error) {
 	return func(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
 		var id int64
-		requestTx := ctx.Value(util.ContextKey("tx")).(string)
-		currentRoute := ctx.Value(util.ContextKey("currentRoute")).(string)
+		requestTx, _ := ctx.Value(util.ContextKey("tx")).(string)
+		currentRoute, _ := ctx.Value(util.ContextKey("currentRoute")).(string)
+		// Only the queries of these routes return the id of the row
+		returning := currentRoute == "CreateSingle" || currentRoute == "UpdateSingle"
+		var tx *sql.Tx
 		if requestTx == "" {
 			// User has not specified an existing transaction to execute within.
 			// However, we will still run the exec statement within a new
 			// transaction
-			tx, err := b.DB.Begin()
+			tx, err = b.DB.Begin()
 			if err != nil {
 				return nil, err
 			}
@@ -147,38 +181,29 @@ func execContext(b *sqlBackend.SqlBackend) func(context.Context, string, ...inte
 				}
 			}()
 		} else {
-			// We assume the transacation is a valid one
+			// We assume the transacation is a valid one. It is committed
+			// by the client once all of its statements were executed.
 			txi, _ := b.Transactions.Load(requestTx)
-			tx := txi.(*sql.Tx)
-			if currentRoute == "DeleteSingle" {
-				result, err = b.DB.ExecContext(ctx, query, args...)
-				if err != nil {
-					return nil, err
-				}
-				tx.Commit()
-				return
-			}
-			if err = b.DB.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
-				return nil, err
-			}
-			result = &lastId{id}
-			tx.Commit()
-			return
+			tx = txi.(*sql.Tx)
 		}
-		if currentRoute == "DeleteSingle" {
-			result, err = b.DB.ExecContext(ctx, query, args...)
+		if !returning {
+			result, err = tx.ExecContext(ctx, query, args...)
 			if err != nil {
 				return nil, err
 			}
-			return
+		} else {
+			if err = tx.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+				return nil, err
+			}
+			result = &lastId{id}
 		}
-		if err = b.DB.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if err = b.CheckRowFilter(ctx, tx, requestTx, result); err != nil {
 			return nil, err
 		}
-		result = &lastId{id}
 		return
 	}
 }
+
 func convertFromPostgresDataType(fieldDataType string) interface{} {
 	switch {
 	case isOfDataType(integer, fieldDataType):
@@ -205,3 +230,39 @@ func isOfDataType(ts []string, fieldDataType string) (result bool) {
 	}
 	return
 }
+
+// isTransientError classifies connection exceptions, serialization
+// failures, deadlocks and a server that is shutting down or starting up
+// as transient
+func isTransientError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"53300", // too_many_connections
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08 contains the connection exceptions
+		return pqErr.Code.Class() == "08"
+	}
+	return sqlBackend.IsTransientError(err)
+}
+
+// isConflictError classifies unique, foreign key and exclusion
+// constraint violations as conflicts
+func isConflictError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503", // foreign_key_violation
+			"23505", // unique_violation
+			"23P01": // exclusion_violation
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
+)
+
+// execProcedure calls the function of an action by selecting from it, so
+// that both the output parameters of the function and the rows of a set
+// returning function are read as a result set
+func (p *Postgres) execProcedure(ctx context.Context, action *descriptor.Action, args ...interface{}) ([]map[string]interface{}, error) {
+	query := fmt.Sprintf(
+		`SELECT * FROM %s(%s)`,
+		action.Procedure, strings.Join(p.ProcedureCall(action), ", "),
+	)
+	log.When(config.Logging()).WithContext(ctx).Debugln(query)
+	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlBackend.ScanProcedureRows(rows, action)
+}
@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+// procedureDateLayouts are the layouts of dates that databases return as
+// strings, for example from the session variables of mysql
+var procedureDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// ProcedureCall returns the placeholders of the arguments of an action's
+// stored procedure, which are the id of the resource followed by the
+// action's parameters. Date parameters are wrapped by the query format
+// functions of the backend, if any.
+func (s *SqlBackend) ProcedureCall(action *descriptor.Action) []string {
+	placeholders := []string{s.FormatPlaceholder(1)}
+	for i, parameter := range action.Parameters {
+		placeholder := s.FormatPlaceholder(i + 2)
+		if format, ok := s.QueryFormatFuncs[parameter.Type.Kind]; ok && parameter.Type.Name == "date" {
+			placeholder = strings.Replace(format(), "%s", placeholder, 1)
+		}
+		placeholders = append(placeholders, placeholder)
+	}
+	return placeholders
+}
+
+// ScanProcedureRows reads the rows of the result set of an action's stored
+// procedure. The columns of the result set are read by the output
+// parameters of the action in the order they are listed, additional
+// columns are ignored.
+func ScanProcedureRows(rows *sql.Rows, action *descriptor.Action) (results []map[string]interface{}, err error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		result := make(map[string]interface{})
+		for i, output := range action.Output {
+			if i >= len(values) {
+				break
+			}
+			result[output.Key] = ProcedureValue(output, *values[i].(*interface{}))
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// ProcedureValue converts a value returned by a stored procedure, either
+// in a result set or in an output parameter, to one of the types that
+// values read from tables have. Output parameters are read from the
+// destinations they were scanned into.
+func ProcedureValue(output *descriptor.Parameter, value interface{}) interface{} {
+	if v, ok := value.(*string); ok {
+		value = *v
+	}
+	if v, ok := value.(driver.Valuer); ok {
+		value, _ = v.Value()
+	}
+	switch v := value.(type) {
+	case []byte:
+		value = string(v)
+	case int:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+	s, ok := value.(string)
+	if !ok || output.Type == nil || output.Type.Name != "date" {
+		return value
+	}
+	for _, layout := range procedureDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return value
+}
@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+)
+
+func TestProcedureValue(t *testing.T) {
+	text := &descriptor.Parameter{Key: "name", Type: &descriptor.WorkflowType{Name: "text"}}
+	number := &descriptor.Parameter{Key: "reserved", Type: &descriptor.WorkflowType{Name: "number"}}
+	date := &descriptor.Parameter{Key: "until", Type: &descriptor.WorkflowType{Name: "date", Kind: "date"}}
+	dateTime := &descriptor.Parameter{Key: "at", Type: &descriptor.WorkflowType{Name: "date", Kind: "datetime"}}
+	name := "Grinder"
+	testCases := []struct {
+		name   string
+		output *descriptor.Parameter
+		value  interface{}
+		want   interface{}
+	}{
+		{"output parameter scanned into a string", text, &name, "Grinder"},
+		{"output parameter scanned into a valuer", number, &sql.NullInt64{Int64: 2, Valid: true}, int64(2)},
+		{"null output parameter", number, &sql.NullFloat64{}, nil},
+		{"bytes", text, []byte("Grinder"), "Grinder"},
+		{"int", number, 2, int64(2)},
+		{"float32", number, float32(1.5), float64(1.5)},
+		{"date string", date, "2021-03-04", time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"datetime string", dateTime, "2021-03-04 10:11:12", time.Date(2021, 3, 4, 10, 11, 12, 0, time.UTC)},
+		{"datetime bytes with offset", dateTime, []byte("2021-03-04T10:11:12+01:00"), time.Date(2021, 3, 4, 9, 11, 12, 0, time.UTC)},
+		{"invalid date string", date, "soon", "soon"},
+		{"date string of a text parameter", text, "2021-03-04", "2021-03-04"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ProcedureValue(tc.output, tc.value)
+			if want, ok := tc.want.(time.Time); ok {
+				if got, ok := got.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("Expected: '%v', got: '%v'", want, got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected: '%#v', got: '%#v'", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScanProcedureRows(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	action := &descriptor.Action{
+		Name:      "reservations",
+		ResultSet: true,
+		Output: []*descriptor.Parameter{
+			{Key: "equipmentName", Type: &descriptor.WorkflowType{Name: "text"}},
+			{Key: "reserved", Type: &descriptor.WorkflowType{Name: "number"}},
+			{Key: "until", Type: &descriptor.WorkflowType{Name: "date", Kind: "date"}},
+		},
+	}
+	// The result set has an additional column, which is ignored
+	rows, err := db.Query(
+		"SELECT 'Grinder', 2, '2021-03-04', 'ignored' " +
+			"UNION ALL SELECT 'Kettle', NULL, NULL, 'ignored'",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	results, err := ScanProcedureRows(rows, action)
+	if err != nil {
+		t.Fatalf("Expected no error, instead got: '%v'", err)
+	}
+	want := []map[string]interface{}{
+		{"equipmentName": "Grinder", "reserved": int64(2), "until": time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"equipmentName": "Kettle", "reserved": nil, "until": nil},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected: '%v', got: '%v'", want, results)
+	}
+	// Output parameters not returned by the result set are missing
+	rows, err = db.Query("SELECT 'Grinder'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	results, err = ScanProcedureRows(rows, action)
+	if err != nil {
+		t.Fatalf("Expected no error, instead got: '%v'", err)
+	}
+	if len(results) != 1 || len(results[0]) != 1 || results[0]["equipmentName"] != "Grinder" {
+		t.Errorf("Expected only the returned columns to be read, got: '%v'", results)
+	}
+}
@@ -3,19 +3,23 @@
 package sql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/signavio/workflow-connector/internal/app/backend"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
 	"github.com/signavio/workflow-connector/internal/pkg/log"
+	"github.com/signavio/workflow-connector/internal/pkg/metrics"
 	"github.com/signavio/workflow-connector/internal/pkg/query"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
 )
@@ -41,6 +45,15 @@ var (
 		}
 		return
 	}
+	coerceArgValueFunc = func(requestData map[string]interface{}, field *descriptor.Field) (result interface{}, ok bool, err error) {
+		if result, ok = requestData[field.Key]; ok {
+			result, err = query.CoerceValue(field.Type, result)
+			if err != nil {
+				return nil, ok, fmt.Errorf("field '%s': %w", field.Key, err)
+			}
+		}
+		return
+	}
 	coerceArgFuncs = map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error){
 		"default": func(requestData map[string]interface{}, field *descriptor.Field) (result interface{}, ok bool, err error) {
 			result, ok = requestData[field.Key]
@@ -58,6 +71,14 @@ var (
 		"datetime": coerceArgDateTimeFunc,
 		"date":     coerceArgDateTimeFunc,
 		"time":     coerceArgDateTimeFunc,
+		"boolean":  coerceArgValueFunc,
+		"number":   coerceArgValueFunc,
+		"text":     coerceArgValueFunc,
+		"email":    coerceArgValueFunc,
+		"link":     coerceArgValueFunc,
+		"user":     coerceArgValueFunc,
+		"choice":   coerceArgValueFunc,
+		"list":     coerceArgValueFunc,
 	}
 )
 
@@ -66,8 +87,13 @@ type SqlBackend struct {
 	DB                     *sql.DB
 	Templates              map[string]string
 	SchemaMapping          map[string]*descriptor.SchemaMapping
-	NewSchemaMapping       func([]string, []*sql.ColumnType) (*descriptor.SchemaMapping, error)
+	NewSchemaMapping       func(*descriptor.Descriptor, []string, []*sql.ColumnType) (*descriptor.SchemaMapping, error)
+	// Introspect reads the schema of the database, which is used to
+	// validate the descriptor before the schema mappings are saved
+	Introspect             introspect.Func
 	Transactions           sync.Map
+	// stopRefresh stops refreshing the options of choice fields
+	stopRefresh            chan struct{}
 }
 
 func New() endpoint.Endpoint {
@@ -75,147 +101,252 @@ func New() endpoint.Endpoint {
 	s.Backend = &backend.Backend{}
 	s.GetSchemaMappingFunc = s.getSchemaMapping
 	s.GetQueryTemplateFunc = s.getQueryTemplate
-	s.CoerceArgFuncs = coerceArgFuncs
+	// Copy the coerce functions, so that the dialects can override
+	// them without affecting each other
+	s.CoerceArgFuncs = make(map[string]func(map[string]interface{}, *descriptor.Field) (interface{}, bool, error))
+	for name, coerceArgFunc := range coerceArgFuncs {
+		s.CoerceArgFuncs[name] = coerceArgFunc
+	}
 	s.OpenFunc = s.open
 	s.CommitTxFunc = s.commitTx
 	s.CreateTxFunc = s.createTx
 	s.QueryContextFunc = s.queryContext
 	s.ExecContextFunc = s.execContext
+	s.PingFunc = s.ping
+	s.CloseFunc = s.close
+	s.ReloadFunc = s.reload
+	s.IsTransientErrorFunc = IsTransientError
+	s.FormatPlaceholder = func(int) string { return "?" }
 	s.SchemaMapping = make(map[string]*descriptor.SchemaMapping)
 	s.NewSchemaMapping = s.newSchemaMapping
+	s.TableSourceFunc = s.tableSource
 	return s
 }
 
 func (s *SqlBackend) open(args ...interface{}) error {
 	driver := args[0].(string)
 	url := args[1].(string)
-	log.When(config.Options.Logging).Infof(
-		"[backend] open connection to database %v\n",
+	log.When(config.Logging()).Infof(
+		"[backend] open connection to database %v at %s\n",
 		driver,
+		config.Redact(url),
 	)
 	db, err := sql.Open(driver, url)
 	if err != nil {
 		return fmt.Errorf("Error opening connection to database: %s", err)
 	}
 	s.DB = db
+	s.ConfigurePool()
+	s.RegisterMetrics(driver)
+	if err := s.ValidateDescriptor(context.Background()); err != nil {
+		return err
+	}
+	if err := s.LoadChoiceOptions(context.Background()); err != nil {
+		return err
+	}
 	err = s.SaveSchemaMapping()
 	if err != nil {
 		return fmt.Errorf("Error saving table schema: %s", err)
 	}
+	s.RefreshChoiceOptions()
+	return nil
+}
+
+func (s *SqlBackend) ping(ctx context.Context) error {
+	if s.DB == nil {
+		return fmt.Errorf("connection to database is not open")
+	}
+	return s.DB.PingContext(ctx)
+}
+
+// reload swaps in the reloaded configuration together with the schema
+// mappings of the tables in the reloaded descriptor
+func (s *SqlBackend) reload() error {
+	return config.Reload(func(cfg *config.Config) (swap func(), err error) {
+		defer func() {
+			// A descriptor that does not match the database must not
+			// take down a running workflow connector
+			if p := recover(); p != nil {
+				err = fmt.Errorf("descriptor does not match the database: %v", p)
+			}
+		}()
+		if err := s.validateDescriptor(context.Background(), cfg.Descriptor); err != nil {
+			return nil, err
+		}
+		if err := s.loadChoiceOptions(context.Background(), cfg.Descriptor); err != nil {
+			return nil, err
+		}
+		schemaMapping, err := s.buildSchemaMapping(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("Error saving table schema: %s", err)
+		}
+		return func() {
+			s.SchemaMapping = schemaMapping
+			s.ConfigurePool()
+			log.When(cfg.Logging).Infoln("[backend] reloaded configuration and table schemas")
+		}, nil
+	})
+}
+
+// ValidateDescriptor checks that the tables and columns referenced in
+// descriptor.json exist in the database, so that mistakes are reported
+// when the descriptor is loaded instead of when a request is handled
+func (s *SqlBackend) ValidateDescriptor(ctx context.Context) error {
+	return s.validateDescriptor(ctx, config.Options.Descriptor)
+}
+
+func (s *SqlBackend) validateDescriptor(ctx context.Context, d *descriptor.Descriptor) error {
+	if s.Introspect == nil {
+		return nil
+	}
+	tables, err := s.Introspect(ctx, s.DB)
+	if err != nil {
+		return fmt.Errorf("Error reading database schema: %s", err)
+	}
+	return introspect.Validate(d, tables)
+}
+
+// SaveSchemaMapping queries the schemas of the tables in the current
+// descriptor and saves them
+func (s *SqlBackend) SaveSchemaMapping() error {
+	schemaMapping, err := s.buildSchemaMapping(&config.Options)
+	if err != nil {
+		return err
+	}
+	s.SchemaMapping = schemaMapping
 	return nil
 }
 
-func (s *SqlBackend) SaveSchemaMapping() (err error) {
-	log.When(config.Options.Logging).Infoln("[backend] query database and save table schemas")
-	for _, table := range config.Options.Database.Tables {
+// buildSchemaMapping queries the schemas of the tables in the descriptor
+// of cfg. It does not depend on the current options, so that the schemas
+// of a reloaded descriptor can be queried while requests are handled.
+func (s *SqlBackend) buildSchemaMapping(cfg *config.Config) (map[string]*descriptor.SchemaMapping, error) {
+	schemaMapping := make(map[string]*descriptor.SchemaMapping)
+	log.When(cfg.Logging).Infoln("[backend] query database and save table schemas")
+	for _, table := range cfg.Database.Tables {
 		// table has no relationships defined in descriptor.json
-		log.When(config.Options.Logging).Infof(
+		log.When(cfg.Logging).Debugf(
 			"[backend] schema for table %v:\n",
 			table.Name,
 		)
-		err := s.populateBackendSchemaMapping(table.Name, "GetTableSchema")
+		err := s.populateBackendSchemaMapping(cfg.Descriptor, schemaMapping, table.Name, "GetTableSchema")
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.When(config.Options.Logging).Infof("%#+v\n", s.SchemaMapping)
+		log.When(cfg.Logging).Debugf("%#+v\n", schemaMapping)
 	}
-	for _, table := range config.Options.Database.Tables {
-		if util.TableHasRelationships(config.Options, table.Name) {
-			log.When(config.Options.Logging).Infof(
+	for _, table := range cfg.Database.Tables {
+		if util.TableHasRelationships(*cfg, table.Name) {
+			log.When(cfg.Logging).Debugf(
 				"[backend] schema for table %v:\n",
 				table.Name+" (with relationships)",
 			)
 
 			td := util.GetTypeDescriptorUsingDBTableName(
-				config.Options.Descriptor.TypeDescriptors,
+				cfg.Descriptor.TypeDescriptors,
 				table.Name,
 			)
 			tdRelationships := util.TypeDescriptorRelationships(td)
 			tdUniqueIdColumn := td.UniqueIdColumn
 			err := s.addRelationshipsToBackendSchemaMapping(
+				cfg.Descriptor,
+				schemaMapping,
 				table.Name,
 				"GetTableWithRelationshipsSchema",
 				tdUniqueIdColumn,
 				tdRelationships,
 			)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
-	log.When(config.Options.Logging).Infof(
+	log.When(cfg.Logging).Debugf(
 		"[backend] the following table schemas were retrieved:\n%#+v\n",
-		s.SchemaMapping,
+		schemaMapping,
 	)
-	return nil
+	return schemaMapping, nil
 }
 func (s *SqlBackend) getQueryTemplate(name string) string {
 	return s.Templates[name]
 }
+// getSchemaMapping returns the schema mapping of a table. The schema
+// mappings are swapped by reload while config.ReloadLock is held for
+// writing.
 func (s *SqlBackend) getSchemaMapping(typeDescriptor string) *descriptor.SchemaMapping {
+	config.ReloadLock.RLock()
+	defer config.ReloadLock.RUnlock()
 	return s.SchemaMapping[typeDescriptor]
 }
-func (s *SqlBackend) populateBackendSchemaMapping(tableName, templateName string) error {
-	template := s.getQueryTemplate(templateName)
-	queryTemplate := &query.QueryTemplate{
-		Vars: []string{template},
-		TemplateData: struct {
-			TableName string
-		}{
-			TableName: tableName,
-		},
-		CoerceArgFuncs: s.GetCoerceArgFuncs(),
-	}
-	query, _, err := queryTemplate.Interpolate(
-		context.WithValue(context.Background(), util.ContextKey("table"), tableName),
-		nil,
-	)
+func (s *SqlBackend) populateBackendSchemaMapping(d *descriptor.Descriptor, schemaMapping map[string]*descriptor.SchemaMapping, tableName, templateName string) error {
+	query, args, err := s.schemaQuery(d, templateName, struct {
+		TableName string
+	}{
+		TableName: tableName,
+	})
 	if err != nil {
 		return err
 	}
-	tableSchema, err := s.retrieveSchemaMapping(query, tableName)
+	tableSchema, err := s.retrieveSchemaMapping(d, query, args, tableName)
 	if err != nil {
 		return fmt.Errorf("Unable to retrieve columns and data types from table schema: %s", err)
 	}
-	s.SchemaMapping[tableName] = tableSchema
+	schemaMapping[tableName] = tableSchema
 	return nil
 }
 
-func (s *SqlBackend) addRelationshipsToBackendSchemaMapping(tableName, templateName, uniqueIDColumn string, relationships []*descriptor.Field) error {
-	template := s.getQueryTemplate(templateName)
-	queryTemplate := &query.QueryTemplate{
-		Vars: []string{template},
-		TemplateData: struct {
-			TableName      string
-			Relations      []*descriptor.Field
-			UniqueIdColumn string
-		}{
-			TableName:      tableName,
-			Relations:      relationships,
-			UniqueIdColumn: uniqueIDColumn,
-		},
-		CoerceArgFuncs: s.GetCoerceArgFuncs(),
-	}
-	query, _, err := queryTemplate.Interpolate(
-		context.WithValue(context.Background(), util.ContextKey("table"), tableName),
-		nil,
-	)
+func (s *SqlBackend) addRelationshipsToBackendSchemaMapping(d *descriptor.Descriptor, schemaMapping map[string]*descriptor.SchemaMapping, tableName, templateName, uniqueIDColumn string, relationships []*descriptor.Field) error {
+	query, args, err := s.schemaQuery(d, templateName, struct {
+		TableName      string
+		Relations      []*descriptor.Field
+		UniqueIdColumn string
+	}{
+		TableName:      tableName,
+		Relations:      relationships,
+		UniqueIdColumn: uniqueIDColumn,
+	})
 	if err != nil {
 		return err
 
 	}
-	tableSchema, err := s.retrieveSchemaMappingWithRelationships(query, tableName)
+	tableSchema, err := s.retrieveSchemaMappingWithRelationships(d, schemaMapping, query, args, tableName)
 	if err != nil {
 		return fmt.Errorf("Unable to retrieve columns and data types from table schema: %s", err)
 	}
-	s.SchemaMapping[fmt.Sprintf("%s\x00relationships", tableName)] = tableSchema
+	schemaMapping[fmt.Sprintf("%s\x00relationships", tableName)] = tableSchema
 	return nil
 }
 
-func (s *SqlBackend) retrieveSchemaMapping(query, table string) (*descriptor.SchemaMapping, error) {
-	log.When(config.Options.Logging).Infoln(query)
+// schemaQuery interpolates the query template retrieving the schema of a
+// table. Tables are selected from as returned by tableSource for the
+// descriptor d. NULL is bound to the parameters of the SQL queries backing
+// type descriptors, since there is no principal whose attributes could be
+// bound when the schema is retrieved.
+func (s *SqlBackend) schemaQuery(d *descriptor.Descriptor, templateName string, data interface{}) (string, []interface{}, error) {
+	queryTemplate, err := template.New(templateName).Funcs(template.FuncMap{
+		"source": func(tableName string) (string, error) {
+			return s.tableSourceFor(config.Options.Database.Driver, d, tableName)
+		},
+	}).Parse(s.getQueryTemplate(templateName))
+	if err != nil {
+		return "", nil, err
+	}
+	q := bytes.NewBufferString("")
+	if err := queryTemplate.Execute(q, data); err != nil {
+		return "", nil, err
+	}
+	return query.InterpolateQueryParameters(
+		q.String(),
+		nil,
+		func(string) (interface{}, error) { return nil, nil },
+		s.FormatPlaceholder,
+	)
+}
+
+func (s *SqlBackend) retrieveSchemaMapping(d *descriptor.Descriptor, query string, args []interface{}, table string) (*descriptor.SchemaMapping, error) {
+	log.When(config.Logging()).Debugln(query)
 
-	rows, err := s.DB.Query(query)
+	rows, err := s.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -239,21 +370,22 @@ func (s *SqlBackend) retrieveSchemaMapping(query, table string) (*descriptor.Sch
 			"unable to get the data types of database table columns",
 		)
 	}
-	return s.NewSchemaMapping(columnsPrepended, columnTypes)
+	return s.NewSchemaMapping(d, columnsPrepended, columnTypes)
 }
 
-func (s *SqlBackend) retrieveSchemaMappingWithRelationships(query, table string) (*descriptor.SchemaMapping, error) {
-	log.When(config.Options.Logging).Infoln(query)
+func (s *SqlBackend) retrieveSchemaMappingWithRelationships(d *descriptor.Descriptor, schemaMapping map[string]*descriptor.SchemaMapping, query string, args []interface{}, table string) (*descriptor.SchemaMapping, error) {
+	log.When(config.Logging()).Debugln(query)
 
-	rows, err := s.DB.Query(query)
+	rows, err := s.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
-	columnsPrepended := prependTableNameToColumnsInJoinedTables(s, table, columns)
+	columnsPrepended := prependTableNameToColumnsInJoinedTables(d, schemaMapping, table, columns)
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
 		return nil, err
@@ -263,7 +395,7 @@ func (s *SqlBackend) retrieveSchemaMappingWithRelationships(query, table string)
 			"unable to get the data types of database table columns",
 		)
 	}
-	return s.NewSchemaMapping(columnsPrepended, columnTypes)
+	return s.NewSchemaMapping(d, columnsPrepended, columnTypes)
 }
 
 // prependTablenameToColumns will prepend the table name to each column name
@@ -282,11 +414,11 @@ func prependTableNameToColumns(table string, columns []string) []string {
 
 // prependTablenameToColumnsInJoinedTables will prepend the table name
 // to each column name on a query result that contains multiple tables
-func prependTableNameToColumnsInJoinedTables(s *SqlBackend, table string, columns []string) []string {
+func prependTableNameToColumnsInJoinedTables(d *descriptor.Descriptor, schemaMapping map[string]*descriptor.SchemaMapping, table string, columns []string) []string {
 	var columnsPrepended []string
-	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, table)
+	td := util.GetTypeDescriptorUsingDBTableName(d.TypeDescriptors, table)
 	fields := util.TypeDescriptorRelationships(td)
-	currentColumnsIdx := len(s.SchemaMapping[table].FieldNames)
+	currentColumnsIdx := len(schemaMapping[table].FieldNames)
 	currentColumns := columns[0:currentColumnsIdx]
 
 	for _, cc := range currentColumns {
@@ -300,7 +432,7 @@ func prependTableNameToColumnsInJoinedTables(s *SqlBackend, table string, column
 	for _, field := range fields {
 		thisTable := field.Relationship.WithTable
 
-		newTableIdx = previousTableIdx + len(s.SchemaMapping[thisTable].FieldNames)
+		newTableIdx = previousTableIdx + len(schemaMapping[thisTable].FieldNames)
 		currentColumns := columns[previousTableIdx:newTableIdx]
 		for _, cc := range currentColumns {
 			columnsPrepended = append(
@@ -313,23 +445,28 @@ func prependTableNameToColumnsInJoinedTables(s *SqlBackend, table string, column
 	return columnsPrepended
 }
 
-func (s *SqlBackend) newSchemaMapping(columnsWithTable []string, columnTypes []*sql.ColumnType) (*descriptor.SchemaMapping, error) {
+func (s *SqlBackend) newSchemaMapping(d *descriptor.Descriptor, columnsWithTable []string, columnTypes []*sql.ColumnType) (*descriptor.SchemaMapping, error) {
 	var backendTypes, golangTypes, workflowTypes []interface{}
 	var fieldNames []string
 	for i := range columnTypes {
 		backendType := columnTypes[i].DatabaseTypeName()
+		var golangType interface{}
 		if backendType == "" {
-			return nil, fmt.Errorf(
-				"unable to get the type in use by the backend",
-			)
+			golangType = fieldColumnType(d, columnsWithTable[i])
+			if golangType == nil {
+				return nil, fmt.Errorf(
+					"unable to get the type in use by the backend",
+				)
+			}
+		} else {
+			golangType = s.CastBackendTypeToGolangType(backendType)
 		}
-		golangType := s.CastBackendTypeToGolangType(backendType)
 		if golangType == nil {
 			return nil, fmt.Errorf(
 				"unable to get the native golang type",
 			)
 		}
-		workflowType := GetWorkflowType(columnsWithTable[i])
+		workflowType := GetWorkflowType(d, columnsWithTable[i])
 		if workflowType == nil {
 			return nil, fmt.Errorf(
 				"unable to get the workflow type specified in descriptor.json",
@@ -343,12 +480,12 @@ func (s *SqlBackend) newSchemaMapping(columnsWithTable []string, columnTypes []*
 	return &descriptor.SchemaMapping{fieldNames, backendTypes, golangTypes, workflowTypes}, nil
 }
 
-func GetWorkflowType(columnWithTable string) interface{} {
+func GetWorkflowType(d *descriptor.Descriptor, columnWithTable string) interface{} {
 	var tableName string
 	tableNamePrefix := strings.IndexRune(columnWithTable, '\x00')
 	tableName = columnWithTable[0:tableNamePrefix]
 	typeDescriptor := util.GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		d.TypeDescriptors,
 		tableName,
 	)
 	for _, field := range typeDescriptor.Fields {
@@ -389,6 +526,40 @@ func (s *SqlBackend) getColumnNamesAndDataTypesForOptionRoutes(table, columnAsOp
 	return columnIDAndName, dataTypesForIDandName
 }
 
+// ConfigurePool applies the connection pool settings in the `database`
+// section of the config file
+func (s *SqlBackend) ConfigurePool() {
+	cfg := config.Options.Database
+	if cfg.MaxOpenConns > 0 {
+		s.DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		s.DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		s.DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		s.DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+}
+
+// RegisterMetrics exposes the connection pool statistics of the database
+// and the number of open transactions as Prometheus metrics
+func (s *SqlBackend) RegisterMetrics(driver string) {
+	openTransactions := func() float64 {
+		var count float64
+		s.Transactions.Range(func(key, value interface{}) bool {
+			count++
+			return true
+		})
+		return count
+	}
+	if err := metrics.RegisterDB(s.DB, driver, openTransactions); err != nil {
+		log.When(config.Logging()).Warnf("[backend] unable to register database metrics: %s\n", err)
+	}
+}
+
 func (s *SqlBackend) LoadTx(key interface{}) (value interface{}, ok bool) {
 	return s.Transactions.Load(key)
 }
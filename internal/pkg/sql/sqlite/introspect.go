@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// Introspect reads the tables, columns, primary keys and foreign keys of
+// the database. SQLite has no information schema, so the schema of every
+// table is read using the table_info and foreign_key_list pragmas.
+func Introspect(ctx context.Context, db *sql.DB) ([]*introspect.Table, error) {
+	names, err := tableNames(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tables: %v", err)
+	}
+	var tables []*introspect.Table
+	for _, name := range names {
+		table := &introspect.Table{Name: name}
+		if err := readColumns(ctx, db, table); err != nil {
+			return nil, fmt.Errorf("unable to read columns of table %s: %v", name, err)
+		}
+		if err := readForeignKeys(ctx, db, table); err != nil {
+			return nil, fmt.Errorf("unable to read foreign keys of table %s: %v", name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func tableNames(ctx context.Context, db *sql.DB) (names []string, err error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT name FROM sqlite_master `+
+			`WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func readColumns(ctx context.Context, db *sql.DB, table *introspect.Table) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, quoteIdentifier(table.Name)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	primaryKey := make(map[int]string)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, dataType   string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		table.Columns = append(table.Columns, &introspect.Column{Name: name, DataType: dataType})
+		// pk is the position of the column in the primary key, or 0
+		if pk > 0 {
+			primaryKey[pk] = name
+		}
+	}
+	for i := 1; i <= len(primaryKey); i++ {
+		table.PrimaryKey = append(table.PrimaryKey, primaryKey[i])
+	}
+	return rows.Err()
+}
+
+func readForeignKeys(ctx context.Context, db *sql.DB, table *introspect.Table) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`PRAGMA foreign_key_list(%s)`, quoteIdentifier(table.Name)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id, seq                   int
+			referencedTable, column   string
+			referencedColumn          sql.NullString
+			onUpdate, onDelete, match string
+		)
+		if err := rows.Scan(&id, &seq, &referencedTable, &column, &referencedColumn, &onUpdate, &onDelete, &match); err != nil {
+			return err
+		}
+		// The referenced column is NULL if the foreign key references
+		// the primary key of the referenced table
+		table.ForeignKeys = append(table.ForeignKeys, &introspect.ForeignKey{
+			Name:             fmt.Sprintf("%s_%d", table.Name, id),
+			Column:           column,
+			ReferencedTable:  referencedTable,
+			ReferencedColumn: referencedColumn.String,
+		})
+	}
+	return rows.Err()
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
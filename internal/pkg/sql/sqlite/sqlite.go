@@ -2,9 +2,10 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
 	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
@@ -14,20 +15,26 @@ import (
 var (
 	QueryTemplates = map[string]string{
 		`GetSingle`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"` +
 			`{{end}}` +
-			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = ?`,
+			` WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = ?` +
+			`{{with .RowFilter}} AND "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetSingleAsOption`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
-			`WHERE "{{.UniqueIdColumn}}" = ?`,
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = ?` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetCollection`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"` +
 			`{{end}}` +
@@ -37,30 +44,43 @@ var (
 			`      AND "_{{$.TableName}}"."{{$element}}" = ? ` +
 			`   {{end}}` +
 			`{{end}}` +
+			`{{with .RowFilter}}{{if $.ColumnNames}} AND{{else}} WHERE{{end}} "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}) {{end}}` +
 			`ORDER BY "_{{.TableName}}"."{{.UniqueIdColumn}}" ASC`,
 		`GetCollectionAsOptions`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
 			`WHERE "{{.ColumnAsOptionName}}" LIKE ? ` +
 			"{{range $index, $element := .ColumnNames}}" +
 			`   AND "{{$.TableName}}"."{{$element}}" = ? ` +
 			"{{end}}" +
+			`{{with .RowFilter}} AND ({{.}}) {{end}}` +
 			`ORDER BY "{{.TableName}}"."{{.UniqueIdColumn}}" ASC`,
 		`UpdateSingle`: `UPDATE "{{.TableName}}" SET "{{.ColumnNames | head}}"` +
 			` = ?{{range .ColumnNames | tail}},`+
 			` "{{.}}" = ?{{end}}`+
-			` WHERE "{{.UniqueIdColumn}}" = ?`,
+			` WHERE "{{.UniqueIdColumn}}" = ?` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`CreateSingle`: `INSERT INTO "{{.TableName}}"`+
 			`("{{.ColumnNames | head}}"` +
 			`{{range .ColumnNames | tail}},`+
 			` "{{.}}"`+
 			`{{end}}) ` +
 			`VALUES(?{{range .ColumnNames | tail}}, ?{{end}})`,
-		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = ?`, `GetTableSchema`: `SELECT * ` +
-			`FROM "{{.TableName}}" ` +
+		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = ?` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`, `GetTableSchema`: `SELECT * ` +
+			`FROM {{source .TableName}} AS "{{.TableName}}" ` +
 			`LIMIT 1`,
-		`GetTableWithRelationshipsSchema`: `SELECT * FROM "{{.TableName}}" AS "_{{.TableName}}" ` +
+		`GetChoiceOptions`: `SELECT "{{.IdColumn}}", "{{.NameColumn}}" ` +
+			`FROM "{{.TableName}}" ` +
+			`ORDER BY "{{.NameColumn}}"`,
+		`CheckRowFilter`: `SELECT COUNT(*) FROM {{source .TableName}} "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = ? AND ({{.RowFilter}})`,
+		`TableSource`: `{{if .Computed}}(SELECT "{{.TableName}}".*` +
+			`{{range .Computed}}, ({{.Expression}}) AS "{{.Column}}"{{end}}` +
+			` FROM {{if .Query}}({{.Query}}) {{end}}"{{.TableName}}")` +
+			`{{else if .Query}}({{.Query}}){{else}}"{{.TableName}}"{{end}}`,
+		`GetTableWithRelationshipsSchema`: `SELECT * FROM {{source .TableName}} AS "_{{.TableName}}" ` +
 			`{{range .Relations}}` +
-			` LEFT JOIN "{{.Relationship.WithTable}}"` +
+			` LEFT JOIN {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}"` +
 			` ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			` = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"{{end}} LIMIT 1`,
 	}
@@ -121,6 +141,10 @@ func New() endpoint.Endpoint {
 	sqliteSpecificArgFuncs["date"] = sqliteDateTimeArgFunc
 	sqliteSpecificArgFuncs["time"] = sqliteDateTimeArgFunc
 	s.CoerceArgFuncs = sqliteSpecificArgFuncs
+	s.IsTransientErrorFunc = isTransientError
+	s.IsConflictErrorFunc = isConflictError
+	s.Introspect = Introspect
+	s.ExplainFunc = s.Explainer("EXPLAIN QUERY PLAN ")
 	return s
 }
 
@@ -150,3 +174,28 @@ func isOfDataType(ts []string, fieldDataType string) (result bool) {
 	}
 	return
 }
+
+// isTransientError classifies a database file or table that is locked by
+// another connection as transient
+func isTransientError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return sqlBackend.IsTransientError(err)
+}
+
+// isConflictError classifies unique, primary key and foreign key
+// constraint violations as conflicts
+func isConflictError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique,
+			sqlite3.ErrConstraintPrimaryKey,
+			sqlite3.ErrConstraintForeignKey:
+			return true
+		}
+	}
+	return false
+}
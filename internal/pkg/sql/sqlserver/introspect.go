@@ -0,0 +1,42 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/signavio/workflow-connector/internal/pkg/introspect"
+)
+
+// IntrospectionQueries read the schema of the tables in the default schema
+// of the current user
+var IntrospectionQueries = introspect.Queries{
+	Columns: "SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE " +
+		"FROM INFORMATION_SCHEMA.COLUMNS c " +
+		"JOIN INFORMATION_SCHEMA.TABLES t " +
+		"ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME " +
+		"WHERE c.TABLE_SCHEMA = SCHEMA_NAME() AND t.TABLE_TYPE = 'BASE TABLE' " +
+		"ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION",
+	PrimaryKeys: "SELECT kcu.TABLE_NAME, kcu.COLUMN_NAME " +
+		"FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc " +
+		"JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu " +
+		"ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME " +
+		"WHERE tc.TABLE_SCHEMA = SCHEMA_NAME() AND tc.CONSTRAINT_TYPE = 'PRIMARY KEY' " +
+		"ORDER BY kcu.TABLE_NAME, kcu.ORDINAL_POSITION",
+	// INFORMATION_SCHEMA.KEY_COLUMN_USAGE lacks the position of a column
+	// in the referenced key, so the catalog views are used instead
+	ForeignKeys: "SELECT OBJECT_NAME(fkc.constraint_object_id), " +
+		"OBJECT_NAME(fkc.parent_object_id), pc.name, " +
+		"OBJECT_NAME(fkc.referenced_object_id), rc.name " +
+		"FROM sys.foreign_key_columns fkc " +
+		"JOIN sys.columns pc " +
+		"ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id " +
+		"JOIN sys.columns rc " +
+		"ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id " +
+		"WHERE OBJECT_SCHEMA_NAME(fkc.parent_object_id) = SCHEMA_NAME()",
+}
+
+// Introspect reads the tables, columns, primary keys and foreign keys of
+// the default schema of the current user
+func Introspect(ctx context.Context, db *sql.DB) ([]*introspect.Table, error) {
+	return introspect.Query(ctx, db, IntrospectionQueries)
+}
@@ -0,0 +1,57 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
+	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
+)
+
+// execProcedure calls the stored procedure of an action as a remote
+// procedure call, which binds the arguments to the procedure's parameters
+// by name. The id of the resource is bound to the action's id parameter.
+func (s *Sqlserver) execProcedure(ctx context.Context, action *descriptor.Action, args ...interface{}) ([]map[string]interface{}, error) {
+	namedArgs := []interface{}{sql.Named(action.IdParameterName(), args[0])}
+	for i, parameter := range action.Parameters {
+		namedArgs = append(namedArgs, sql.Named(parameter.Key, args[i+1]))
+	}
+	log.When(config.Logging()).WithContext(ctx).Debugf("EXEC %s\n", action.Procedure)
+	if action.ResultSet {
+		rows, err := s.DB.QueryContext(ctx, action.Procedure, namedArgs...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return sqlBackend.ScanProcedureRows(rows, action)
+	}
+	destinations := make([]interface{}, len(action.Output))
+	for i, output := range action.Output {
+		destinations[i] = outputDestination(output)
+		namedArgs = append(namedArgs, sql.Named(output.Key, sql.Out{Dest: destinations[i]}))
+	}
+	if _, err := s.DB.ExecContext(ctx, action.Procedure, namedArgs...); err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	for i, output := range action.Output {
+		result[output.Key] = sqlBackend.ProcedureValue(output, destinations[i])
+	}
+	return []map[string]interface{}{result}, nil
+}
+
+// outputDestination returns what the value of an output parameter is
+// scanned into. The driver derives the type of the parameter from it, and
+// dates are read as text, which sql server converts them to implicitly.
+func outputDestination(output *descriptor.Parameter) interface{} {
+	switch output.Type.Name {
+	case "number":
+		return &sql.NullFloat64{}
+	case "boolean":
+		return &sql.NullBool{}
+	default:
+		return &sql.NullString{}
+	}
+}
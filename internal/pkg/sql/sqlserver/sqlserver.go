@@ -2,9 +2,11 @@ package sqlserver
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 
-	_ "github.com/denisenkom/go-mssqldb"
+	mssql "github.com/denisenkom/go-mssqldb"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	sqlBackend "github.com/signavio/workflow-connector/internal/pkg/sql"
 	"github.com/signavio/workflow-connector/internal/pkg/util"
@@ -13,20 +15,26 @@ import (
 var (
 	QueryTemplates = map[string]string{
 		`GetSingle`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}" ` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"` +
 			`{{end}}` +
-			`WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = @p1`,
+			`WHERE "_{{$.TableName}}"."{{.UniqueIdColumn}}" = @p1` +
+			`{{with .RowFilter}} AND "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetSingleAsOption`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
-			`WHERE "{{.UniqueIdColumn}}" = @p1`,
+			`FROM {{source .TableName}} AS "_{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = @p1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetCollection`: `SELECT * ` +
-			`FROM "{{.TableName}}" AS "_{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}" ` +
 			`{{range .Relations}}` +
-			`   LEFT JOIN "{{.Relationship.WithTable}}"` +
+			`   LEFT JOIN {{if rowFilter .Relationship.WithTable}}` +
+			`(SELECT * FROM {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}" WHERE {{rowFilter .Relationship.WithTable}})` +
+			`{{else}}{{source .Relationship.WithTable}}{{end}} "{{.Relationship.WithTable}}"` +
 			`   ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			`   = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}" ` +
 			`{{end}}` +
@@ -35,19 +43,22 @@ var (
 			`   {{range $index, $element := . | tail}}` +
 			`      AND "_{{$.TableName}}"."{{$element}}" = @p{{(add2 $index)}} ` +
 			`   {{end}}` +
-			`{{end}}`,
+			`{{end}}` +
+			`{{with .RowFilter}}{{if $.ColumnNames}} AND{{else}} WHERE{{end}} "_{{$.TableName}}"."{{$.UniqueIdColumn}}" IN (SELECT "{{$.UniqueIdColumn}}" FROM {{source $.TableName}} "{{$.TableName}}" WHERE {{.}}){{end}}`,
 		`GetCollectionAsOptions`: `SELECT "{{.UniqueIdColumn}}", "{{.ColumnAsOptionName}}" ` +
-			`FROM "{{.TableName}}" ` +
+			`FROM {{source .TableName}} AS "_{{.TableName}}" ` +
 			`WHERE CAST ("{{.ColumnAsOptionName}}" AS TEXT) LIKE @p1 ` +
 			`{{range $index, $element := .ColumnNames}}` +
 			`   AND "_{{$.TableName}}"."{{$element}}" = @p{{(add2 $index)}}` +
-			`{{end}}`,
+			`{{end}}` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`UpdateSingle`: `UPDATE "{{.TableName}}" ` +
 			`SET "{{.ColumnNames | head}}" = @p1` +
 			`{{range $index, $element := .ColumnNames | tail}},` +
 			`  "{{$element}}" = @p{{(add2 $index)}}` +
 			`{{end}} ` +
-			`WHERE "{{.UniqueIdColumn}}"= @p{{(lenPlus1 .ColumnNames)}}`,
+			`WHERE "{{.UniqueIdColumn}}"= @p{{(lenPlus1 .ColumnNames)}}` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`CreateSingle`: `INSERT INTO "{{.TableName}}"` +
 			`("{{.ColumnNames | head}}"` +
 			`{{range .ColumnNames | tail}},` +
@@ -58,12 +69,22 @@ var (
 			`  @p{{$index | add2}}` +
 			`{{end}}) ` +
 			`RETURNING "{{.UniqueIdColumn}}"`,
-		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = ?`,
+		`DeleteSingle`: `DELETE FROM "{{.TableName}}" WHERE "{{.UniqueIdColumn}}" = @p1` +
+			`{{with .RowFilter}} AND ({{.}}){{end}}`,
 		`GetTableSchema`: `SELECT TOP 1 * ` +
-			`FROM "{{.TableName}}"`,
-		`GetTableWithRelationshipsSchema`: `SELECT TOP 1 * FROM "{{.TableName}}" AS "_{{.TableName}}"` +
+			`FROM {{source .TableName}} AS "{{.TableName}}"`,
+		`GetChoiceOptions`: `SELECT "{{.IdColumn}}", "{{.NameColumn}}" ` +
+			`FROM "{{.TableName}}" ` +
+			`ORDER BY "{{.NameColumn}}"`,
+		`CheckRowFilter`: `SELECT COUNT(*) FROM {{source .TableName}} "{{.TableName}}" ` +
+			`WHERE "{{.UniqueIdColumn}}" = @p1 AND ({{.RowFilter}})`,
+		`TableSource`: `{{if .Computed}}(SELECT "{{.TableName}}".*` +
+			`{{range .Computed}}, ({{.Expression}}) AS "{{.Column}}"{{end}}` +
+			` FROM {{if .Query}}({{.Query}}) {{end}}"{{.TableName}}")` +
+			`{{else if .Query}}({{.Query}}){{else}}"{{.TableName}}"{{end}}`,
+		`GetTableWithRelationshipsSchema`: `SELECT TOP 1 * FROM {{source .TableName}} AS "_{{.TableName}}"` +
 			`{{range .Relations}}` +
-			` LEFT JOIN "{{.Relationship.WithTable}}"` +
+			` LEFT JOIN {{source .Relationship.WithTable}} "{{.Relationship.WithTable}}"` +
 			` ON "{{.Relationship.WithTable}}"."{{.Relationship.ForeignTableUniqueIdColumn}}"` +
 			` = "_{{$.TableName}}"."{{.Relationship.LocalTableUniqueIdColumn}}"{{end}}`,
 	}
@@ -109,6 +130,13 @@ type Sqlserver struct {
 func New() endpoint.Endpoint {
 	s := &Sqlserver{sqlBackend.New().(*sqlBackend.SqlBackend)}
 	s.Templates = QueryTemplates
+	s.FormatPlaceholder = func(position int) string {
+		return fmt.Sprintf("@p%d", position)
+	}
+	s.IsTransientErrorFunc = isTransientError
+	s.IsConflictErrorFunc = isConflictError
+	s.Introspect = Introspect
+	s.ExecProcedureFunc = s.execProcedure
 	return s
 }
 
@@ -136,3 +164,37 @@ func isOfDataType(ts []string, fieldDataType string) (result bool) {
 	}
 	return
 }
+
+// isTransientError classifies deadlocks and the transient errors of Azure
+// SQL Database, which occur during failovers, as transient
+func isTransientError(err error) bool {
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		switch mssqlErr.Number {
+		case 1205, // deadlock victim
+			4060,                // cannot open database
+			40197,               // error processing the request
+			40501,               // service is busy
+			40613,               // database is not currently available
+			49918, 49919, 49920: // not enough resources
+			return true
+		}
+		return false
+	}
+	return sqlBackend.IsTransientError(err)
+}
+
+// isConflictError classifies unique and foreign key constraint violations
+// as conflicts
+func isConflictError(err error) bool {
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		switch mssqlErr.Number {
+		case 547, // statement conflicted with a constraint
+			2601, // duplicate key row in object with unique index
+			2627: // violation of unique key or primary key constraint
+			return true
+		}
+	}
+	return false
+}
@@ -63,6 +63,7 @@ var (
 }`
 	conformityTests = map[string][]testCase{
 		"GetDescriptor": getDescriptorTestCases,
+		"Health":        healthTestCases,
 	}
 	getDescriptorTestCases = []testCase{
 		{
@@ -93,4 +94,48 @@ var (
 			},
 		},
 	}
+	healthTestCases = []testCase{
+		{
+			Kind:                "success",
+			Name:                "it succeeds when the database is alive",
+			ExpectedStatusCodes: []int{http.StatusOK},
+			ExpectedResults: []string{`{
+  "status": "ok"
+}`},
+			Request: func() *http.Request {
+				req, _ := http.NewRequest("GET", "/healthz", nil)
+				return req
+			},
+		},
+		{
+			Kind:                "success",
+			Name:                "it succeeds when the database is ready",
+			ExpectedStatusCodes: []int{http.StatusOK},
+			ExpectedResults: []string{`{
+  "status": "ok",
+  "checks": {
+    "circuitBreaker": {
+      "status": "ok",
+      "latencyMs": %s
+    },
+    "database": {
+      "status": "ok",
+      "latencyMs": %s
+    },
+    "schemaMappings": {
+      "status": "ok",
+      "latencyMs": %s
+    }
+  }
+}`,
+				"[0-9.e-]+",
+				"[0-9.e-]+",
+				"[0-9.e-]+",
+			},
+			Request: func() *http.Request {
+				req, _ := http.NewRequest("GET", "/readyz", nil)
+				return req
+			},
+		},
+	}
 )
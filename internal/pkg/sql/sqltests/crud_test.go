@@ -73,7 +73,8 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 404,
-    "description": "Resource with uniqueID '42' not found in equipment table"
+    "description": "Resource with uniqueID '42' not found in equipment",
+    "error": "not_found"
   }
 }`},
 			Request: func() *http.Request {
@@ -306,6 +307,26 @@ var (
 				return req
 			},
 		},
+		{
+			Kind:                "failure",
+			Name:                "it fails and returns 409 CONFLICT when creating a resource with an existing id",
+			ExpectedStatusCodes: []int{http.StatusConflict},
+			ExpectedResults: []string{`{
+  "status": {
+    "code": 409,
+    "description": "The request conflicts with existing data, for example a duplicate unique ID or a missing related resource",
+    "error": "conflict"
+  }
+}`},
+			Request: func() *http.Request {
+				postData := url.Values{}
+				postData.Set("id", "5")
+				postData.Set("name", "French Press")
+				req, _ := http.NewRequest("POST", "/equipment", strings.NewReader(postData.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+		},
 		{
 			Kind: "success",
 			Name: "it returns a 200 OK with the newly created resource or a 204 No Content when provided with valid URL parameters on POST in the funny column names table",
@@ -464,7 +485,8 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 404,
-    "description": "Resource with uniqueID '42' not found in equipment table"
+    "description": "Resource with uniqueID '42' not found in equipment",
+    "error": "not_found"
   }
 }
 `},
@@ -485,7 +507,7 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 200,
-    "description": "Resource with uniqueID '5' successfully deleted from equipment table"
+    "description": "Resource with uniqueID '5' successfully deleted from equipment"
   }
 }`},
 			Request: func() *http.Request {
@@ -500,7 +522,7 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 200,
-    "description": "Resource with uniqueID '1' successfully deleted from zero_rows table"
+    "description": "Resource with uniqueID '1' successfully deleted from zeroRows"
   }
 }`},
 			Request: func() *http.Request {
@@ -515,7 +537,7 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 200,
-    "description": "Resource with uniqueID '2' successfully deleted from funny column names table"
+    "description": "Resource with uniqueID '2' successfully deleted from funnyColumnNames"
   }
 }`},
 			Request: func() *http.Request {
@@ -531,7 +553,8 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 404,
-    "description": "Resource with uniqueID '42' not found in equipment table"
+    "description": "Resource with uniqueID '42' not found in equipment",
+    "error": "not_found"
   }
 }`},
 			Request: func() *http.Request {
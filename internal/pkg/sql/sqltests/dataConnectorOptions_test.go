@@ -30,7 +30,8 @@ var (
 			ExpectedResults: []string{`{
   "status": {
     "code": 404,
-    "description": "Resource with uniqueID '42' not found in equipment table"
+    "description": "Resource with uniqueID '42' not found in equipment",
+    "error": "not_found"
   }
 }`},
 
@@ -1,24 +1,36 @@
 package sqltests
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
 	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/descriptor"
+	"github.com/signavio/workflow-connector/internal/pkg/log"
 	"github.com/signavio/workflow-connector/internal/pkg/middleware"
 	"github.com/signavio/workflow-connector/internal/pkg/sql/mysql"
 	"github.com/signavio/workflow-connector/internal/pkg/sql/oracle"
 	"github.com/signavio/workflow-connector/internal/pkg/sql/postgres"
 	"github.com/signavio/workflow-connector/internal/pkg/sql/sqlite"
 	"github.com/signavio/workflow-connector/internal/pkg/sql/sqlserver"
+	"github.com/signavio/workflow-connector/internal/pkg/util"
 	"github.com/spf13/viper"
 )
 
@@ -89,8 +101,481 @@ func testSqlBackend(t *testing.T, name, driver string, newEndpointFunc func() en
 		for testName, testCases := range dataConnectorOptionsTests {
 			runTestCases(t, testName, testCases, ts, endpoint)
 		}
+		t.Run("StatementTimeout", func(t *testing.T) {
+			testStatementTimeout(t, ts)
+		})
+		t.Run("SlowQueryLog", func(t *testing.T) {
+			testSlowQueryLog(t, ts)
+		})
+		t.Run("ChoiceOptions", func(t *testing.T) {
+			testChoiceOptions(t, endpoint, ts)
+		})
+		t.Run("ComputedFields", func(t *testing.T) {
+			testComputedFields(t, endpoint, ts)
+		})
+		t.Run("QueryTypeDescriptor", func(t *testing.T) {
+			testQueryTypeDescriptor(t, endpoint, ts)
+		})
+		t.Run("Actions", func(t *testing.T) {
+			testActions(t, ts)
+		})
+		t.Run("Audit", func(t *testing.T) {
+			testAudit(t, ts, driver, viper.Get(name+".database.url").(string))
+		})
+		t.Run("RowFilter", func(t *testing.T) {
+			testRowFilter(t, ts)
+		})
+	})
+	t.Run("Closing "+name+" database", func(t *testing.T) {
+		testClose(t, endpoint)
+	})
+}
+
+// testStatementTimeout asserts that requests are answered with 504 Gateway
+// Timeout if the database does not respond within the statement timeout
+func testStatementTimeout(t *testing.T, ts *httptest.Server) {
+	previous := config.Options.Database.StatementTimeout
+	config.Options.Database.StatementTimeout = time.Nanosecond
+	defer func() { config.Options.Database.StatementTimeout = previous }()
+	req, _ := http.NewRequest("GET", ts.URL+"/equipment/1", nil)
+	req.SetBasicAuth(config.Options.Auth.Username, "Foobar")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expected HTTP %d, instead we received: %d", http.StatusGatewayTimeout, res.StatusCode)
+	}
+}
+
+// testChoiceOptions asserts that the options of a choice field are loaded
+// from its lookup table, served with the descriptor and enforced on writes
+func testChoiceOptions(t *testing.T, e endpoint.Endpoint, ts *httptest.Server) {
+	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, "inventory")
+	field := td.Fields[2]
+	previous := field.Type
+	field.Type = &descriptor.WorkflowType{
+		Name: "choice",
+		OptionsFromTable: &descriptor.OptionsFromTable{
+			TableName:  "equipment",
+			IdColumn:   "id",
+			NameColumn: "name",
+		},
+	}
+	defer func() { field.Type = previous }()
+	b := e.(interface {
+		LoadChoiceOptions(context.Context) error
+	})
+	if err := b.LoadChoiceOptions(context.Background()); err != nil {
+		t.Fatalf("Expected choice options to be loaded, got error: %s", err)
+	}
+	found := false
+	for _, option := range field.Type.Options {
+		if option.Id == "1" && option.Name == "Bialetti Moka Express 6 cup" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected options to contain the first equipment, got: %+v", field.Type.Options)
+	}
+	req, _ := http.NewRequest("GET", ts.URL+"/", nil)
+	req.SetBasicAuth(config.Options.Auth.Username, "Foobar")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), `"name": "Bialetti Moka Express 6 cup"`) {
+		t.Errorf("Expected descriptor to contain the loaded options, got: %s", body)
+	}
+	req, _ = http.NewRequest("PATCH", ts.URL+"/inventory/1?unitOfMeasure=Each", nil)
+	req.SetBasicAuth(config.Options.Auth.Username, "Foobar")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "expected one of the options") {
+		t.Errorf("Expected HTTP %d for a value that is not an option, instead we received: %d %s", http.StatusBadRequest, res.StatusCode, body)
+	}
+}
+
+// testComputedFields asserts that computed fields are returned and can be
+// filtered on, but are rejected in the request data of writes
+func testComputedFields(t *testing.T, e endpoint.Endpoint, ts *httptest.Server) {
+	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, "equipment")
+	previous := td.Fields
+	td.Fields = append(td.Fields[:len(td.Fields):len(td.Fields)], &descriptor.Field{
+		Key:        "upperName",
+		Name:       "Name in upper case",
+		FromColumn: "upper_name",
+		Type:       &descriptor.WorkflowType{Name: "text"},
+		Expression: descriptor.Expression{"default": "UPPER(name)"},
+	})
+	b := e.(interface {
+		SaveSchemaMapping() error
+	})
+	defer func() {
+		td.Fields = previous
+		if err := b.SaveSchemaMapping(); err != nil {
+			t.Errorf("Expected table schemas to be saved, got error: %s", err)
+		}
+	}()
+	if err := b.SaveSchemaMapping(); err != nil {
+		t.Fatalf("Expected table schemas to be saved, got error: %s", err)
+	}
+	status, body := doRequest(t, ts, "GET", "/equipment/1", nil)
+	var equipment map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &equipment); err != nil || status != http.StatusOK {
+		t.Fatalf("Expected the equipment to be returned, instead we received: %d %s", status, body)
+	}
+	upperName, _ := equipment["name"].(string)
+	upperName = strings.ToUpper(upperName)
+	if equipment["upperName"] != upperName {
+		t.Errorf("Expected the computed field to be '%s', instead we received: %s", upperName, body)
+	}
+	status, body = doRequest(t, ts, "GET", "/equipment?upperName="+url.QueryEscape(upperName), nil)
+	var collection []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &collection); err != nil || status != http.StatusOK ||
+		len(collection) == 0 || collection[0]["id"] != "1" {
+		t.Errorf("Expected the collection to be filtered by the computed field, instead we received: %d %s", status, body)
+	}
+	status, body = doRequest(t, ts, "PATCH", "/equipment/1?upperName=FOO", nil)
+	if status != http.StatusBadRequest || !strings.Contains(body, "upperName") {
+		t.Errorf("Expected HTTP %d when writing a computed field, instead we received: %d %s", http.StatusBadRequest, status, body)
+	}
+}
+
+// testQueryTypeDescriptor asserts that type descriptors backed by an SQL
+// query can be read, filtered and listed as options, but not written
+func testQueryTypeDescriptor(t *testing.T, e endpoint.Endpoint, ts *httptest.Server) {
+	td := &descriptor.TypeDescriptor{
+		Key: "equipmentUsage",
+		Query: `SELECT e.id, e.name, COUNT(r.id) AS recipe_count ` +
+			`FROM equipment e LEFT JOIN recipes r ON r.equipment_id = e.id ` +
+			`WHERE e.name <> {{principal.username}} ` +
+			`GROUP BY e.id, e.name`,
+		UniqueIdColumn:     "id",
+		ColumnAsOptionName: "name",
+		Fields: []*descriptor.Field{
+			{Key: "id", FromColumn: "id", Type: &descriptor.WorkflowType{Name: "text"}},
+			{Key: "name", FromColumn: "name", Type: &descriptor.WorkflowType{Name: "text"}},
+			{Key: "recipeCount", FromColumn: "recipe_count", Type: &descriptor.WorkflowType{Name: "number"}},
+		},
+	}
+	previousTypeDescriptors := config.Options.Descriptor.TypeDescriptors
+	previousTables := config.Options.Database.Tables
+	config.Options.Descriptor.TypeDescriptors = append(previousTypeDescriptors[:len(previousTypeDescriptors):len(previousTypeDescriptors)], td)
+	config.Options.Database.Tables = append(previousTables[:len(previousTables):len(previousTables)], &config.Table{Name: td.Table(), ColumnAsOptionName: td.ColumnAsOptionName})
+	b := e.(interface {
+		SaveSchemaMapping() error
+	})
+	defer func() {
+		config.Options.Descriptor.TypeDescriptors = previousTypeDescriptors
+		config.Options.Database.Tables = previousTables
+		if err := b.SaveSchemaMapping(); err != nil {
+			t.Errorf("Expected table schemas to be saved, got error: %s", err)
+		}
+	}()
+	if err := b.SaveSchemaMapping(); err != nil {
+		t.Fatalf("Expected the schema of the query to be saved, got error: %s", err)
+	}
+	status, body := doRequest(t, ts, "GET", "/equipmentUsage", nil)
+	var collection []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &collection); err != nil || status != http.StatusOK || len(collection) == 0 {
+		t.Fatalf("Expected the rows of the query to be returned, instead we received: %d %s", status, body)
+	}
+	if _, ok := collection[0]["recipeCount"].(float64); !ok {
+		t.Errorf("Expected the recipe count to be a number, instead we received: %s", body)
+	}
+	name, _ := collection[0]["name"].(string)
+	status, body = doRequest(t, ts, "GET", "/equipmentUsage?name="+url.QueryEscape(name), nil)
+	if status != http.StatusOK || !strings.Contains(body, fmt.Sprintf(`"id": "%s"`, collection[0]["id"])) {
+		t.Errorf("Expected the rows of the query to be filtered, instead we received: %d %s", status, body)
+	}
+	status, body = doRequest(t, ts, "GET", "/equipmentUsage/options?filter="+url.QueryEscape(name), nil)
+	if status != http.StatusOK || !strings.Contains(body, name) {
+		t.Errorf("Expected the rows of the query to be listed as options, instead we received: %d %s", status, body)
+	}
+	for _, method := range []string{"POST", "PATCH", "DELETE"} {
+		path := "/equipmentUsage"
+		if method != "POST" {
+			path += "/1"
+		}
+		status, body = doRequest(t, ts, method, path+"?name=foo", nil)
+		if status != http.StatusMethodNotAllowed || !strings.Contains(body, "read_only") {
+			t.Errorf("Expected HTTP %d for %s %s, instead we received: %d %s", http.StatusMethodNotAllowed, method, path, status, body)
+		}
+	}
+	td.Query = strings.Replace(td.Query, "{{principal.username}}", "{{principal.plant}}", 1)
+	status, body = doRequest(t, ts, "GET", "/equipmentUsage", nil)
+	if status != http.StatusForbidden || !strings.Contains(body, "plant") {
+		t.Errorf("Expected HTTP %d for a missing attribute, instead we received: %d %s", http.StatusForbidden, status, body)
+	}
+}
+
+// testActions asserts that actions call the `reserve_equipment` procedure
+// created by the migration scripts, and are answered with 501 Not
+// Implemented by databases without stored procedures
+func testActions(t *testing.T, ts *httptest.Server) {
+	td := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, "equipment")
+	previous := td.Actions
+	td.Actions = []*descriptor.Action{{
+		Name:        "reserve",
+		Procedure:   "reserve_equipment",
+		IdParameter: "equipment_id",
+		Parameters: []*descriptor.Parameter{
+			{Key: "quantity", Type: &descriptor.WorkflowType{Name: "number"}},
+		},
+		Output: []*descriptor.Parameter{
+			{Key: "equipment_name", Type: &descriptor.WorkflowType{Name: "text"}},
+			{Key: "reserved", Type: &descriptor.WorkflowType{Name: "number"}},
+		},
+	}}
+	defer func() { td.Actions = previous }()
+	quantity := url.Values{"quantity": {"2"}}
+	status, body := doRequest(t, ts, "POST", "/equipment/1/actions/repair", quantity)
+	if status != http.StatusNotFound || !strings.Contains(body, "repair") {
+		t.Errorf("Expected HTTP %d for an unknown action, instead we received: %d %s", http.StatusNotFound, status, body)
+	}
+	status, body = doRequest(t, ts, "POST", "/equipment/9999/actions/reserve", quantity)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected HTTP %d for an unknown resource, instead we received: %d %s", http.StatusNotFound, status, body)
+	}
+	status, body = doRequest(t, ts, "POST", "/equipment/1/actions/reserve", url.Values{"quantity": {"two"}})
+	if status != http.StatusBadRequest || !strings.Contains(body, "quantity") {
+		t.Errorf("Expected HTTP %d for an invalid parameter, instead we received: %d %s", http.StatusBadRequest, status, body)
+	}
+	status, body = doRequest(t, ts, "POST", "/equipment/1/actions/reserve", quantity)
+	switch config.Options.Database.Driver {
+	case "sqlite3":
+		if status != http.StatusNotImplemented || !strings.Contains(body, "not_implemented") {
+			t.Errorf("Expected HTTP %d, instead we received: %d %s", http.StatusNotImplemented, status, body)
+		}
+	case "sqlserver":
+		// There is no migration script creating the procedure
+	default:
+		var output map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &output); err != nil || status != http.StatusOK ||
+			output["reserved"] != float64(2) || output["equipment_name"] == nil {
+			t.Errorf("Expected the output parameters of the procedure, instead we received: %d %s", status, body)
+		}
+	}
+}
+
+// testAudit asserts that audit records are inserted into a database table,
+// and that the writes within a transaction are only recorded once the
+// transaction was committed
+func testAudit(t *testing.T, ts *httptest.Server, driver, dataSourceName string) {
+	db, err := sql.Open(driver, dataSourceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	occurredAt := "TIMESTAMP"
+	if driver == "sqlserver" {
+		occurredAt = "DATETIME2"
+	}
+	if _, err := db.Exec(fmt.Sprintf(
+		"CREATE TABLE audit_log (occurred_at %s, principal VARCHAR(255), operation VARCHAR(32), "+
+			"type_descriptor VARCHAR(255), record_id VARCHAR(255), tx VARCHAR(36), changes VARCHAR(4000))",
+		occurredAt,
+	)); err != nil {
+		t.Fatalf("Expected the audit table to be created, got error: %s", err)
+	}
+	defer db.Exec("DROP TABLE audit_log")
+	previous := config.Options.Audit
+	config.Options.Audit = config.Audit{Enabled: true, Table: "audit_log"}
+	defer func() { config.Options.Audit = previous }()
+	// records waits up to timeout until the audit table contains at least
+	// n records, which are written in the background, and returns them as
+	// `operation id tx changes`
+	records := func(n int, timeout time.Duration) (records []string) {
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			rows, err := db.Query("SELECT operation, record_id, tx, changes FROM audit_log")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			records = nil
+			for rows.Next() {
+				var operation, id, tx, changes sql.NullString
+				if err := rows.Scan(&operation, &id, &tx, &changes); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				records = append(records, strings.Join([]string{operation.String, id.String, tx.String, changes.String}, " "))
+			}
+			rows.Close()
+			if len(records) >= n {
+				return
+			}
+		}
+		return
+	}
+
+	status, body := doRequest(t, ts, "POST", "/equipment", url.Values{"name": {"Audited"}})
+	var created map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &created); err != nil || status != http.StatusCreated {
+		t.Fatalf("Expected the equipment to be created, instead we received: %d %s", status, body)
+	}
+	id, _ := created["id"].(string)
+	status, body = doRequest(t, ts, "PATCH", "/equipment/1?name=Audited", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the equipment to be updated, instead we received: %d %s", status, body)
+	}
+	got := records(2, 5*time.Second)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "CreateSingle "+id+"  ") ||
+		!strings.HasPrefix(got[1], "UpdateSingle 1  ") || !strings.Contains(got[1], `"after":"Audited"`) {
+		t.Fatalf("Expected the writes to be recorded, instead we recorded: %q", got)
+	}
+	status, body = doRequest(t, ts, "POST", "/?begin", nil)
+	var begin struct{ Status struct{ Tx string } }
+	if err := json.Unmarshal([]byte(body), &begin); err != nil || begin.Status.Tx == "" {
+		t.Fatalf("Expected a transaction to be created, instead we received: %d %s", status, body)
+	}
+	tx := begin.Status.Tx
+	status, body = doRequest(t, ts, "DELETE", "/equipment/"+id+"?tx="+tx, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the equipment to be deleted, instead we received: %d %s", status, body)
+	}
+	if got := records(3, 100*time.Millisecond); len(got) != 2 {
+		t.Errorf("Expected the delete not to be recorded before the commit, instead we recorded: %q", got)
+	}
+	status, body = doRequest(t, ts, "POST", "/?commit="+tx, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the transaction to be committed, instead we received: %d %s", status, body)
+	}
+	got = records(4, 5*time.Second)
+	if len(got) != 4 || !strings.HasPrefix(got[2], "DeleteSingle "+id+" "+tx) || !strings.HasPrefix(got[3], "CommitTx  "+tx) {
+		t.Errorf("Expected the delete within the transaction to be recorded on commit, instead we recorded: %q", got)
+	}
+}
+
+// testRowFilter asserts that rows written outside of the row filter of
+// their type descriptor are rejected, and that related rows are filtered
+// by the row filter of their own type descriptor
+func testRowFilter(t *testing.T, ts *httptest.Server) {
+	equipment := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, "equipment")
+	recipes := util.GetTypeDescriptorUsingDBTableName(config.Options.Descriptor.TypeDescriptors, "recipes")
+	previousEquipment, previousRecipes := equipment.RowFilter, recipes.RowFilter
+	equipment.RowFilter = "name <> {{principal.username}}"
+	recipes.RowFilter = "1 = 0"
+	defer func() { equipment.RowFilter, recipes.RowFilter = previousEquipment, previousRecipes }()
+	hidden := config.Options.Auth.Username
+
+	status, body := doRequest(t, ts, "POST", "/equipment", url.Values{"name": {hidden}})
+	if status != http.StatusForbidden {
+		t.Errorf("Expected HTTP %d when creating a filtered row, instead we received: %d %s", http.StatusForbidden, status, body)
+	}
+	status, body = doRequest(t, ts, "PATCH", "/equipment/1?name="+url.QueryEscape(hidden), nil)
+	if status != http.StatusForbidden {
+		t.Errorf("Expected HTTP %d when updating a row out of the filter, instead we received: %d %s", http.StatusForbidden, status, body)
+	}
+	equipment.RowFilter = ""
+	status, body = doRequest(t, ts, "GET", "/equipment?name="+url.QueryEscape(hidden), nil)
+	if status != http.StatusOK || strings.TrimSpace(body) != "[]" {
+		t.Errorf("Expected the rejected writes to be rolled back, instead we received: %d %s", status, body)
+	}
+	equipment.RowFilter = "name <> {{principal.username}}"
+
+	status, body = doRequest(t, ts, "POST", "/equipment", url.Values{"name": {"Visible"}})
+	var created map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &created); err != nil || status != http.StatusCreated {
+		t.Fatalf("Expected the equipment to be created, instead we received: %d %s", status, body)
+	}
+	defer doRequest(t, ts, "DELETE", fmt.Sprintf("/equipment/%s", created["id"]), nil)
+	if recipes, ok := created["recipes"].([]interface{}); !ok || len(recipes) != 0 {
+		t.Errorf("Expected no recipes for new equipment, instead we received: %s", body)
+	}
+
+	status, body = doRequest(t, ts, "GET", "/equipment/2", nil)
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &got); err != nil || status != http.StatusOK {
+		t.Fatalf("Expected the equipment to be returned, instead we received: %d %s", status, body)
+	}
+	if recipes, ok := got["recipes"].([]interface{}); !ok || len(recipes) != 0 {
+		t.Errorf("Expected the related recipes to be filtered, instead we received: %s", body)
+	}
+}
 
+// testSlowQueryLog asserts that slow queries are logged with redacted
+// arguments and, on databases supporting it, with their query plan
+func testSlowQueryLog(t *testing.T, ts *httptest.Server) {
+	previous := config.Options.Database.SlowQueryLog
+	config.Options.Database.SlowQueryLog = config.SlowQueryLog{
+		Threshold:         time.Nanosecond,
+		ExplainSampleRate: 1,
+	}
+	defer func() { config.Options.Database.SlowQueryLog = previous }()
+	out := &syncBuffer{}
+	log.SetOutput(out)
+	defer log.SetOutput(os.Stdout)
+	req, _ := http.NewRequest("GET", ts.URL+"/equipment/1", nil)
+	req.SetBasicAuth(config.Options.Auth.Username, "Foobar")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	logged := out.String()
+	if !strings.Contains(logged, "slow query took") ||
+		!strings.Contains(logged, "route=GetSingle typeDescriptor=equipment rows=1 args=[string(len=1)]") {
+		t.Errorf("expected slow query to be logged, instead we logged:\n%s", logged)
+	}
+	switch config.Options.Database.Driver {
+	case "sqlite3", "postgres", "mysql":
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), "query plan of slow query") && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if logged := out.String(); !strings.Contains(logged, "query plan of slow query") {
+			t.Errorf("expected query plan to be logged, instead we logged:\n%s", logged)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer that can be written to by the background
+// goroutines of the backend while being read by a test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// testClose asserts that open transactions are rolled back and the
+// connection to the database is closed
+func testClose(t *testing.T, e endpoint.Endpoint) {
+	b := e.(interface {
+		CreateTx(time.Duration) (uuid.UUID, error)
+		LoadTx(interface{}) (interface{}, bool)
+		Ping(context.Context) error
 	})
+	txUUID, err := b.CreateTx(time.Minute)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Expected database to be closed, got error: %s", err)
+	}
+	if _, ok := b.LoadTx(txUUID.String()); ok {
+		t.Errorf("Expected open transaction to be removed from backend")
+	}
+	if err := b.Ping(context.Background()); err == nil {
+		t.Errorf("Expected connection to database to be closed")
+	}
 }
 func runTestCases(t *testing.T, testName string, testCases []testCase, ts *httptest.Server, endpoint endpoint.Endpoint) {
 	t.Run(testName, func(t *testing.T) {
@@ -207,6 +692,29 @@ func itSucceeds(tc testCase, ts *httptest.Server) error {
 	}
 	return nil
 }
+
+// doRequest sends an authenticated request to the test server, with the
+// data encoded as a form in the body if it is not nil, and returns the
+// status code and body of the response
+func doRequest(t *testing.T, ts *httptest.Server, method, path string, data url.Values) (int, string) {
+	var body io.Reader
+	if data != nil {
+		body = strings.NewReader(data.Encode())
+	}
+	req, _ := http.NewRequest(method, ts.URL+path, body)
+	if data != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.SetBasicAuth(config.Options.Auth.Username, "Foobar")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	got, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, string(got)
+}
+
 func newTestServer(e endpoint.Endpoint) *httptest.Server {
 	router := e.GetHandler().(*mux.Router)
 	ts := httptest.NewUnstartedServer(router)
@@ -0,0 +1,82 @@
+// Package tracing sets up OpenTelemetry tracing and exports the spans
+// recorded by the workflow connector over OTLP
+package tracing
+
+import (
+	"context"
+
+	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/signavio/workflow-connector"
+
+func init() {
+	// Incoming W3C trace context is propagated even if spans are not
+	// exported, so that the trace of the caller is not interrupted
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Init exports spans to the OTLP/HTTP endpoint configured in the `tracing`
+// section of the config file. The returned function flushes the spans
+// that have not been exported yet and should be called on shutdown.
+func Init(cfg config.Tracing) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	options := []otlptracehttp.Option{}
+	if cfg.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "workflow-connector"
+	}
+	sampleRatio := cfg.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(sampleRatio),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the given name as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
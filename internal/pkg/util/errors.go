@@ -0,0 +1,64 @@
+package util
+
+import "net/http"
+
+// ErrorCode tells clients why a request failed. Unlike the description of
+// a response message, error codes are stable and can be relied upon.
+type ErrorCode string
+
+const (
+	// ErrorCodeNotFound is used if the requested resource does not exist
+	ErrorCodeNotFound ErrorCode = "not_found"
+	// ErrorCodeValidationFailed is used if the request data is invalid
+	ErrorCodeValidationFailed ErrorCode = "validation_failed"
+	// ErrorCodeConflict is used if the request violates a constraint of
+	// the database, such as a unique key or a foreign key
+	ErrorCodeConflict ErrorCode = "conflict"
+	// ErrorCodeForbidden is used if the client may not access the resource
+	ErrorCodeForbidden ErrorCode = "forbidden"
+	// ErrorCodeReadOnly is used if the client tries to change a resource
+	// of a type descriptor backed by a view or an SQL query
+	ErrorCodeReadOnly ErrorCode = "read_only"
+	// ErrorCodeNotImplemented is used if the database does not support
+	// the requested operation, such as calling a stored procedure
+	ErrorCodeNotImplemented ErrorCode = "not_implemented"
+	// ErrorCodeRateLimited is used if the client sent too many requests
+	ErrorCodeRateLimited ErrorCode = "rate_limited"
+	// ErrorCodeTimeout is used if the database did not respond in time
+	ErrorCodeTimeout ErrorCode = "timeout"
+	// ErrorCodeDBUnavailable is used if the database can not be reached
+	ErrorCodeDBUnavailable ErrorCode = "db_unavailable"
+	// ErrorCodeInternal is used for all other errors
+	ErrorCodeInternal ErrorCode = "internal"
+)
+
+var errorCodeStatus = map[ErrorCode]int{
+	ErrorCodeNotFound:         http.StatusNotFound,
+	ErrorCodeValidationFailed: http.StatusBadRequest,
+	ErrorCodeConflict:         http.StatusConflict,
+	ErrorCodeForbidden:        http.StatusForbidden,
+	ErrorCodeReadOnly:         http.StatusMethodNotAllowed,
+	ErrorCodeNotImplemented:   http.StatusNotImplemented,
+	ErrorCodeRateLimited:      http.StatusTooManyRequests,
+	ErrorCodeTimeout:          http.StatusGatewayTimeout,
+	ErrorCodeDBUnavailable:    http.StatusServiceUnavailable,
+	ErrorCodeInternal:         http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status code of responses with this error code
+func (c ErrorCode) Status() int {
+	if status, ok := errorCodeStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewError returns the response message of a failed request. The message
+// is returned to the client and must not contain details of the database.
+func NewError(code ErrorCode, msg string) *ResponseMessage {
+	return &ResponseMessage{
+		Code:      code.Status(),
+		ErrorCode: code,
+		Msg:       msg,
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -20,11 +22,24 @@ import (
 type ContextKey string
 
 type ResponseMessage struct {
-	Code int
-	Tx   string
-	Msg  string
+	Code      int
+	ErrorCode ErrorCode
+	Tx        string
+	Msg       string
 }
 
+// Principal is the authenticated user on whose behalf a request is served
+type Principal struct {
+	Username   string
+	Attributes map[string]string
+}
+
+// RowFilterCheck checks, within the transaction tx of a write operation,
+// that the row written with the given result still matches the row filter
+// of its type descriptor. It is passed to the backend in the request's
+// context as `rowFilterCheck`.
+type RowFilterCheck func(ctx context.Context, tx *sql.Tx, result sql.Result) error
+
 type NullTime struct {
 	Time  time.Time
 	Valid bool // Valid is true if Time is not NULL
@@ -42,6 +57,9 @@ func (rm *ResponseMessage) Byte() []byte {
 			"description": rm.Msg,
 		},
 	}
+	if rm.ErrorCode != "" {
+		msg["status"].(map[string]interface{})["error"] = rm.ErrorCode
+	}
 	if rm.Tx != "" {
 		msg["status"].(map[string]interface{})["tx"] = rm.Tx
 	}
@@ -60,9 +78,9 @@ func (rm *ResponseMessage) Error() string {
 
 // GetColumnNamesFromRequestData will, for every parameter in the request data,
 // retrieve the corresponding table column name
-func GetColumnNamesFromRequestData(tableName string, requestData map[string]interface{}) (columnNames []string) {
+func GetColumnNamesFromRequestData(ctx context.Context, tableName string, requestData map[string]interface{}) (columnNames []string) {
 	td := GetTypeDescriptorUsingDBTableName(
-		config.Options.Descriptor.TypeDescriptors,
+		config.Current(ctx).Descriptor.TypeDescriptors,
 		tableName,
 	)
 	for _, field := range td.Fields {
@@ -85,7 +103,7 @@ func GetColumnNamesFromRequestData(tableName string, requestData map[string]inte
 // file defined for the table provided in the function's second parameter
 func GetTypeDescriptorUsingDBTableName(typeDescriptors []*descriptor.TypeDescriptor, tableName string) (td *descriptor.TypeDescriptor) {
 	for _, typeDescriptor := range typeDescriptors {
-		if tableName == typeDescriptor.TableName {
+		if tableName == typeDescriptor.Table() {
 			td = typeDescriptor
 		}
 	}
@@ -96,7 +114,7 @@ func GetTypeDescriptorUsingDBTableName(typeDescriptors []*descriptor.TypeDescrip
 func GetDBTableNameUsingTypeDescriptorKey(typeDescriptors []*descriptor.TypeDescriptor, typeDescriptorKey string) (tableName string, ok bool) {
 	for _, typeDescriptor := range typeDescriptors {
 		if typeDescriptorKey == typeDescriptor.Key {
-			tableName = typeDescriptor.TableName
+			tableName = typeDescriptor.Table()
 			return tableName, true
 		}
 	}
@@ -146,7 +164,7 @@ func ContextWithRelationships(ctx context.Context, typeDescriptors []*descriptor
 func TableHasRelationships(cfg config.Config, table string) bool {
 	result := false
 	td := GetTypeDescriptorUsingDBTableName(cfg.Descriptor.TypeDescriptors, table)
-	if td.TableName == table {
+	if td.Table() == table {
 		if TypeDescriptorRelationships(td) != nil {
 			result = true
 		}
@@ -245,6 +263,24 @@ func IsOptionRoute(req *http.Request) bool {
 	return false
 }
 
+// Attribute returns the value of the principal's attribute with the given
+// name. Attribute names are case insensitive since the config file
+// parser does not preserve the case of map keys.
+func (p *Principal) Attribute(name string) (value string, ok bool) {
+	if p == nil {
+		return "", false
+	}
+	if strings.EqualFold(name, "username") {
+		return p.Username, true
+	}
+	for k, v := range p.Attributes {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 // Scan implements the Scanner interface.
 func (nt *NullTime) Scan(value interface{}) error {
 	nt.Time, nt.Valid = value.(time.Time)
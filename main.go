@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kardianos/service"
 	"github.com/signavio/workflow-connector/internal/app"
+	"github.com/signavio/workflow-connector/internal/app/endpoint"
 	"github.com/signavio/workflow-connector/internal/app/server"
 	"github.com/signavio/workflow-connector/internal/pkg/config"
+	"github.com/signavio/workflow-connector/internal/pkg/tracing"
 	"github.com/spf13/viper"
 )
 
@@ -18,48 +24,118 @@ var (
 	logger  service.Logger
 )
 
+// defaultDrainTimeout is used if no drain timeout is set in the config file
+const defaultDrainTimeout = 30 * time.Second
+
 type App struct {
-	server *http.Server
+	endpoint        endpoint.Endpoint
+	server          *http.Server
+	metricsServer   *http.Server
+	shutdownTracing func(context.Context) error
 }
 
 func (a *App) Start(s service.Service) error {
 	logger.Infof("starting workflow connector %s\n", version)
+	// The endpoint and the servers are created before Start returns, since
+	// Stop may be called as soon as it returned
+	if err := a.setup(); err != nil {
+		return err
+	}
 	go a.run()
 	return nil
 }
 func (a *App) Stop(s service.Service) error {
 	logger.Infof("\nstopping workflow connector %s\n", version)
-	if err := a.server.Shutdown(context.Background()); err != nil {
-		logger.Infof("unable to shutdown server cleanly: %s\n", err)
+	config.ReloadLock.RLock()
+	drainTimeout := config.Options.Shutdown.DrainTimeout
+	config.ReloadLock.RUnlock()
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if a.server != nil {
+		if err := a.server.Shutdown(ctx); err != nil {
+			logger.Infof(
+				"unable to drain requests within %s, closing connections: %s\n",
+				drainTimeout, err,
+			)
+			a.server.Close()
+		}
+	}
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(context.Background()); err != nil {
+			logger.Infof("unable to shutdown metrics server cleanly: %s\n", err)
+		}
+	}
+	if a.endpoint != nil {
+		if err := a.endpoint.Close(); err != nil {
+			logger.Infof("unable to close connection to database cleanly: %s\n", err)
+		}
+	}
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(context.Background()); err != nil {
+			logger.Infof("unable to flush traces: %s\n", err)
+		}
 	}
 	if service.Interactive() {
 		os.Exit(0)
 	}
 	return nil
 }
-func (a *App) run() {
+
+// setup initializes tracing, opens the connection to the database and
+// creates the servers, which are started by run
+func (a *App) setup() error {
+	shutdownTracing, err := tracing.Init(config.Options.Tracing)
+	if err != nil {
+		return fmt.Errorf("unable to initialize tracing: %s", err)
+	}
+	a.shutdownTracing = shutdownTracing
 	endpoint, err := app.NewEndpoint(config.Options)
 	if err != nil {
-		logger.Errorf("unable to create new endpoint: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to create new endpoint: %s", err)
 	}
 	err = endpoint.Open(
 		config.Options.Database.Driver,
 		config.Options.Database.URL,
 	)
 	if err != nil {
-		logger.Errorf("unable to initialize backend: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to initialize backend: %s", err)
 	}
+	a.endpoint = endpoint
 	a.server = server.NewServer(config.Options, endpoint)
+	a.metricsServer = server.NewMetricsServer(config.Options)
+	return nil
+}
+
+func (a *App) run() {
+	// The options are copied before they can be swapped by a reload
+	cfg := config.Options
+	go a.reloadOnHangup()
+	if err := config.Watch(a.reload); err != nil {
+		logger.Warningf("unable to watch config files for changes: %s\n", err)
+	}
+	if a.metricsServer != nil {
+		go func() {
+			logger.Infof(
+				"metrics are available at %s/metrics\n",
+				cfg.Metrics.Address,
+			)
+			err := a.metricsServer.ListenAndServe()
+			if err != http.ErrServerClosed {
+				logger.Errorf("unable to start metrics server: %s\n", err)
+			}
+		}()
+	}
 	logger.Infof(
 		"server is ready and listening on port %s\n",
-		config.Options.Port,
+		cfg.Port,
 	)
-	if config.Options.TLS.Enabled {
+	if cfg.TLS.Enabled {
 		err := a.server.ListenAndServeTLS(
-			config.Options.TLS.PublicKey,
-			config.Options.TLS.PrivateKey,
+			cfg.TLS.PublicKey,
+			cfg.TLS.PrivateKey,
 		)
 		if err != http.ErrServerClosed {
 			logger.Errorf("unable to start http server: %s\n", err)
@@ -74,7 +150,47 @@ func (a *App) run() {
 	}
 }
 
+// reloadOnHangup reloads the configuration whenever the process receives
+// SIGHUP, instead of exiting
+func (a *App) reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		a.reload()
+	}
+}
+
+// reload reads config.yml and descriptor.json again, keeping the current
+// configuration if either is invalid
+func (a *App) reload() {
+	if err := a.endpoint.Reload(); err != nil {
+		logger.Errorf("unable to reload config, keeping current config: %s\n", err)
+		return
+	}
+	logger.Infof("reloaded config\n")
+}
+
 func main() {
+	switch flag.Arg(0) {
+	case "introspect":
+		if err := app.Introspect(config.Options, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "introspect: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	case "openapi":
+		if err := app.OpenAPI(config.Options, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "openapi: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	case "validate":
+		if err := app.Validate(config.Options, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	a := &App{}
 	serviceControl, ok := viper.Get("service").(string)
 	if ok && serviceControl == "install" {